@@ -0,0 +1,45 @@
+// Command contract-schemas writes the JSON Schema of every message contract to
+// a directory, or checks the current contracts against those snapshots.
+//
+//	contract-schemas [-dir pkg/contracts/schemas] [-check] [-mode full]
+//
+// Run it without -check after adding a contract version to record its schema;
+// run it with -check in CI to catch incompatible changes.
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/whotterre/entritts/pkg/contracts"
+)
+
+func main() {
+	dir := flag.String("dir", "pkg/contracts/schemas", "directory holding the schema snapshots")
+	check := flag.Bool("check", false, "verify the contracts against the snapshots instead of writing them")
+	mode := flag.String("mode", "full", "compatibility to require: backward, forward or full")
+	flag.Parse()
+
+	if !*check {
+		if err := contracts.WriteSnapshots(*dir); err != nil {
+			log.Fatalf("Failed to write schemas: %v", err)
+		}
+		return
+	}
+
+	var compatibility contracts.Compatibility
+	switch *mode {
+	case "backward":
+		compatibility = contracts.Backward
+	case "forward":
+		compatibility = contracts.Forward
+	case "full":
+		compatibility = contracts.Full
+	default:
+		log.Fatalf("unknown mode %q", *mode)
+	}
+
+	if err := contracts.VerifySnapshots(*dir, compatibility); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/whotterre/entritts/pkg/rabbitmq"
+)
+
+type server struct {
+	manager *rabbitmq.DeadLetterManager
+	token   string
+}
+
+type replayRequest struct {
+	IDs []string `json:"ids"`
+	// Bodies optionally replaces the payload of a message, keyed by message ID
+	Bodies map[string]json.RawMessage `json:"bodies,omitempty"`
+}
+
+type purgeRequest struct {
+	IDs []string `json:"ids"`
+	All bool     `json:"all"`
+}
+
+func newServer(manager *rabbitmq.DeadLetterManager, token string) *server {
+	return &server{manager: manager, token: token}
+}
+
+func (s *server) listen(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("GET /dead-letters/{queue}", s.requireToken(s.list))
+	mux.HandleFunc("POST /dead-letters/{queue}/replay", s.requireToken(s.replay))
+	mux.HandleFunc("POST /dead-letters/{queue}/purge", s.requireToken(s.purge))
+	return http.ListenAndServe(addr, mux)
+}
+
+// requireToken guards the API with a static bearer token
+func (s *server) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	expected := []byte("Bearer " + s.token)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			writeError(w, http.StatusUnauthorized, "Unauthorized", "missing or invalid token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// list handles GET /dead-letters/{queue}?limit=n
+func (s *server) list(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			writeError(w, http.StatusBadRequest, "Invalid limit", "limit must be a positive integer")
+			return
+		}
+		limit = parsed
+	}
+
+	letters, err := s.manager.List(r.PathValue("queue"), limit)
+	if err != nil {
+		log.Printf("Failed to list dead letters: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to list dead letters", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"data": letters})
+}
+
+// replay handles POST /dead-letters/{queue}/replay
+func (s *server) replay(w http.ResponseWriter, r *http.Request) {
+	var req replayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+	if len(req.IDs) == 0 {
+		writeError(w, http.StatusBadRequest, "Invalid request body", "ids is required")
+		return
+	}
+
+	bodies := make(map[string][]byte, len(req.Bodies))
+	for id, body := range req.Bodies {
+		bodies[id] = body
+	}
+
+	replayed, err := s.manager.Replay(r.Context(), r.PathValue("queue"), req.IDs, bodies)
+	if err != nil {
+		log.Printf("Failed to replay dead letters: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to replay dead letters", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"replayed": replayed, "requested": len(req.IDs)})
+}
+
+// purge handles POST /dead-letters/{queue}/purge
+func (s *server) purge(w http.ResponseWriter, r *http.Request) {
+	var req purgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+	if len(req.IDs) == 0 && !req.All {
+		writeError(w, http.StatusBadRequest, "Invalid request body", "pass ids or set all to true")
+		return
+	}
+
+	purged, err := s.manager.Purge(r.PathValue("queue"), req.IDs)
+	if err != nil {
+		log.Printf("Failed to purge dead letters: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to purge dead letters", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"purged": purged})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message, details string) {
+	writeJSON(w, status, map[string]string{"error": message, "details": details})
+}
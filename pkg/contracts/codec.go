@@ -0,0 +1,134 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/whotterre/entritts/pkg/contracts/pb"
+	"github.com/whotterre/entritts/pkg/rabbitmq"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// protoPayload is implemented by contracts that have a protobuf encoding
+type protoPayload interface {
+	marshalProto() ([]byte, error)
+}
+
+// protoTarget is implemented by pointers to contracts that have a protobuf encoding
+type protoTarget interface {
+	unmarshalProto(data []byte) error
+}
+
+// SupportsProtobuf reports whether T can be encoded as protobuf
+func SupportsProtobuf[T Contract]() bool {
+	var zero T
+	_, ok := any(zero).(protoPayload)
+	return ok
+}
+
+// Marshal encodes env as contentType and returns the content type used.
+// Contracts without a protobuf encoding fall back to JSON.
+func Marshal[T Contract](env Envelope[T], contentType string) ([]byte, string, error) {
+	switch contentType {
+	case "", rabbitmq.ContentTypeJSON:
+		body, err := json.Marshal(env)
+		return body, rabbitmq.ContentTypeJSON, err
+	case rabbitmq.ContentTypeProtobuf:
+		payload, ok := any(env.Data).(protoPayload)
+		if !ok {
+			body, err := json.Marshal(env)
+			return body, rabbitmq.ContentTypeJSON, err
+		}
+		data, err := payload.marshalProto()
+		if err != nil {
+			return nil, "", err
+		}
+		body, err := proto.Marshal(&pb.Envelope{
+			Id:            env.ID,
+			Type:          env.Type,
+			Version:       int32(env.Version),
+			OccurredAt:    timestamppb.New(env.OccurredAt),
+			Producer:      env.Producer,
+			CorrelationId: env.CorrelationID,
+			Data:          data,
+		})
+		return body, rabbitmq.ContentTypeProtobuf, err
+	}
+	return nil, "", fmt.Errorf("%w %q", rabbitmq.ErrUnsupportedContentType, contentType)
+}
+
+// Unmarshal parses body, encoded as contentType, as an envelope of T, rejecting
+// other types and versions
+func Unmarshal[T Contract](contentType string, body []byte) (Envelope[T], error) {
+	var env Envelope[T]
+	switch contentType {
+	case "", rabbitmq.ContentTypeJSON:
+		if err := json.Unmarshal(body, &env); err != nil {
+			return env, err
+		}
+	case rabbitmq.ContentTypeProtobuf:
+		target, ok := any(&env.Data).(protoTarget)
+		if !ok {
+			return env, fmt.Errorf("%w %q for %s", rabbitmq.ErrUnsupportedContentType, contentType, env.Data.MessageType())
+		}
+		var message pb.Envelope
+		if err := proto.Unmarshal(body, &message); err != nil {
+			return env, err
+		}
+		env.ID = message.GetId()
+		env.Type = message.GetType()
+		env.Version = int(message.GetVersion())
+		env.OccurredAt = message.GetOccurredAt().AsTime()
+		env.Producer = message.GetProducer()
+		env.CorrelationID = message.GetCorrelationId()
+		if err := checkHeader[T](env.Type, env.Version); err != nil {
+			return env, err
+		}
+		if err := target.unmarshalProto(message.GetData()); err != nil {
+			return env, err
+		}
+		return env, nil
+	default:
+		return env, fmt.Errorf("%w %q", rabbitmq.ErrUnsupportedContentType, contentType)
+	}
+	return env, checkHeader[T](env.Type, env.Version)
+}
+
+func checkHeader[T Contract](messageType string, version int) error {
+	var zero T
+	if messageType != zero.MessageType() {
+		return fmt.Errorf("%w: got %q, want %q", ErrTypeMismatch, messageType, zero.MessageType())
+	}
+	if version != zero.MessageVersion() {
+		return fmt.Errorf("%w: %s v%d", ErrUnknownVersion, messageType, version)
+	}
+	return nil
+}
+
+// peekHeader reads the type and version of an envelope without decoding its payload
+func peekHeader(contentType string, body []byte) (header, error) {
+	var h header
+	switch contentType {
+	case "", rabbitmq.ContentTypeJSON:
+		err := json.Unmarshal(body, &h)
+		return h, err
+	case rabbitmq.ContentTypeProtobuf:
+		var message pb.Envelope
+		if err := proto.Unmarshal(body, &message); err != nil {
+			return h, err
+		}
+		return header{ID: message.GetId(), Type: message.GetType(), Version: int(message.GetVersion())}, nil
+	}
+	return h, fmt.Errorf("%w %q", rabbitmq.ErrUnsupportedContentType, contentType)
+}
+
+// transcode converts a protobuf envelope of T to JSON so it can be checked
+// against T's schema
+func transcode[T Contract](body []byte) ([]byte, error) {
+	env, err := Unmarshal[T](rabbitmq.ContentTypeProtobuf, body)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(env)
+}
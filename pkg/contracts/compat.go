@@ -0,0 +1,164 @@
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Compatibility is the guarantee a new schema gives relative to the previous one
+type Compatibility int
+
+const (
+	// Backward: consumers on the new schema can read messages written with the old one
+	Backward Compatibility = iota
+	// Forward: consumers on the old schema can read messages written with the new one
+	Forward
+	// Full is both Backward and Forward
+	Full
+)
+
+func (c Compatibility) String() string {
+	switch c {
+	case Backward:
+		return "backward"
+	case Forward:
+		return "forward"
+	default:
+		return "full"
+	}
+}
+
+// CheckCompatibility lists the changes from previous to current that break mode.
+// An empty result means the change is compatible.
+func CheckCompatibility(previous, current *Schema, mode Compatibility) []string {
+	var problems []string
+	if mode == Backward || mode == Full {
+		// Data written with previous must be readable as current
+		compare("$", previous, current, "backward", &problems)
+	}
+	if mode == Forward || mode == Full {
+		compare("$", current, previous, "forward", &problems)
+	}
+	return problems
+}
+
+// compare reports where documents valid against writer may be rejected by reader
+func compare(path string, writer, reader *Schema, direction string, problems *[]string) {
+	report := func(format string, args ...any) {
+		*problems = append(*problems, fmt.Sprintf("%s (%s): %s", path, direction, fmt.Sprintf(format, args...)))
+	}
+
+	if len(reader.Type) > 0 {
+		if len(writer.Type) == 0 {
+			report("type narrowed to %s", strings.Join(reader.Type, " or "))
+		}
+		for _, t := range writer.Type {
+			if !reader.Type.allows(t) {
+				report("%s is no longer accepted", t)
+			}
+		}
+	}
+	if reader.Format != "" && reader.Format != writer.Format {
+		report("format changed to %q", reader.Format)
+	}
+	if reader.Const != nil && fmt.Sprint(reader.Const) != fmt.Sprint(writer.Const) {
+		report("constant changed to %v", reader.Const)
+	}
+
+	required := make(map[string]bool, len(writer.Required))
+	for _, name := range writer.Required {
+		required[name] = true
+	}
+	for _, name := range reader.Required {
+		if !required[name] {
+			report("field %q is required but may be missing", name)
+		}
+	}
+
+	names := make([]string, 0, len(reader.Properties))
+	for name := range reader.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if prop, ok := writer.Properties[name]; ok {
+			compare(path+"."+name, prop, reader.Properties[name], direction, problems)
+		}
+	}
+
+	if writer.Items != nil && reader.Items != nil {
+		compare(path+"[]", writer.Items, reader.Items, direction, problems)
+	}
+	if writer.AdditionalProperties != nil && reader.AdditionalProperties != nil {
+		compare(path+".*", writer.AdditionalProperties, reader.AdditionalProperties, direction, problems)
+	}
+}
+
+// WriteSnapshots saves every registered schema to dir as <id>.json
+func WriteSnapshots(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, schema := range Schemas() {
+		data, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, schema.ID+".json")
+		if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifySnapshots checks the registered schemas against the snapshots in dir, so
+// a test can fail when a contract changes incompatibly:
+//
+//	if err := contracts.VerifySnapshots("schemas", contracts.Full); err != nil {
+//		t.Fatal(err)
+//	}
+//
+// Contracts without a snapshot are new and pass; a snapshot without a
+// registered contract means a version was removed and fails.
+func VerifySnapshots(dir string, mode Compatibility) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		var previous Schema
+		if err := json.Unmarshal(data, &previous); err != nil {
+			return fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+
+		current, ok := registry[previous.ID]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: contract was removed", previous.ID))
+			continue
+		}
+		for _, problem := range CheckCompatibility(&previous, current, mode) {
+			problems = append(problems, previous.ID+": "+problem)
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("incompatible contract changes:\n" + strings.Join(problems, "\n"))
+	}
+	return nil
+}
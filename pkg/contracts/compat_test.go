@@ -0,0 +1,106 @@
+package contracts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSnapshotsAreCompatible(t *testing.T) {
+	if err := VerifySnapshots("schemas", Full); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	object := func(required []string, properties map[string]*Schema) *Schema {
+		return &Schema{Type: SchemaType{"object"}, Properties: properties, Required: required}
+	}
+	str := &Schema{Type: SchemaType{"string"}}
+	nullableStr := &Schema{Type: SchemaType{"string", "null"}}
+	number := &Schema{Type: SchemaType{"number"}}
+	integer := &Schema{Type: SchemaType{"integer"}}
+
+	tests := []struct {
+		name     string
+		previous *Schema
+		current  *Schema
+		mode     Compatibility
+		// want is a substring of the single expected problem, or "" for none
+		want string
+	}{
+		{
+			name:     "unchanged",
+			previous: object([]string{"id"}, map[string]*Schema{"id": str}),
+			current:  object([]string{"id"}, map[string]*Schema{"id": str}),
+			mode:     Full,
+		},
+		{
+			name:     "optional field added",
+			previous: object([]string{"id"}, map[string]*Schema{"id": str}),
+			current:  object([]string{"id"}, map[string]*Schema{"id": str, "note": str}),
+			mode:     Full,
+		},
+		{
+			name:     "required field removed, backward",
+			previous: object([]string{"id", "name"}, map[string]*Schema{"id": str, "name": str}),
+			current:  object([]string{"id"}, map[string]*Schema{"id": str}),
+			mode:     Backward,
+		},
+		{
+			name:     "required field removed, forward",
+			previous: object([]string{"id", "name"}, map[string]*Schema{"id": str, "name": str}),
+			current:  object([]string{"id"}, map[string]*Schema{"id": str}),
+			mode:     Forward,
+			want:     `$ (forward): field "name" is required but may be missing`,
+		},
+		{
+			name:     "field newly required",
+			previous: object([]string{"id"}, map[string]*Schema{"id": str, "name": str}),
+			current:  object([]string{"id", "name"}, map[string]*Schema{"id": str, "name": str}),
+			mode:     Backward,
+			want:     `$ (backward): field "name" is required but may be missing`,
+		},
+		{
+			name:     "type narrowed",
+			previous: object(nil, map[string]*Schema{"note": nullableStr}),
+			current:  object(nil, map[string]*Schema{"note": str}),
+			mode:     Backward,
+			want:     "$.note (backward): null is no longer accepted",
+		},
+		{
+			name:     "number narrowed to integer",
+			previous: object(nil, map[string]*Schema{"price": number}),
+			current:  object(nil, map[string]*Schema{"price": integer}),
+			mode:     Backward,
+			want:     "$.price (backward): number is no longer accepted",
+		},
+		{
+			name:     "integer widened to number",
+			previous: object(nil, map[string]*Schema{"price": integer}),
+			current:  object(nil, map[string]*Schema{"price": number}),
+			mode:     Backward,
+		},
+		{
+			name:     "type added where there was none",
+			previous: object(nil, map[string]*Schema{"meta": {}}),
+			current:  object(nil, map[string]*Schema{"meta": str}),
+			mode:     Backward,
+			want:     "$.meta (backward): type narrowed to string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := CheckCompatibility(tt.previous, tt.current, tt.mode)
+			if tt.want == "" {
+				if len(problems) != 0 {
+					t.Fatalf("expected no problems, got %q", problems)
+				}
+				return
+			}
+			if len(problems) != 1 || !strings.Contains(problems[0], tt.want) {
+				t.Fatalf("expected %q, got %q", tt.want, problems)
+			}
+		})
+	}
+}
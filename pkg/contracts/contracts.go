@@ -0,0 +1,27 @@
+// Package contracts defines the messages services exchange over RabbitMQ. Each
+// routing key has a versioned struct; a breaking change adds a new version
+// rather than editing an existing one.
+package contracts
+
+import "errors"
+
+// Exchanges messages are published to
+const (
+	ExchangeEvents  = "events"
+	ExchangeTickets = "tickets"
+)
+
+var (
+	ErrUnknownType    = errors.New("unknown message type")
+	ErrUnknownVersion = errors.New("unknown message version")
+	ErrTypeMismatch   = errors.New("message type does not match contract")
+)
+
+// Contract is implemented by every message payload
+type Contract interface {
+	// Exchange is where the message is published
+	Exchange() string
+	// MessageType is the routing key, e.g. "event.created"
+	MessageType() string
+	MessageVersion() int
+}
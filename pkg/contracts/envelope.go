@@ -0,0 +1,59 @@
+package contracts
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whotterre/entritts/pkg/rabbitmq"
+)
+
+// Envelope wraps every payload with the metadata consumers need to route,
+// deduplicate and trace it
+type Envelope[T Contract] struct {
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	Version       int       `json:"version"`
+	OccurredAt    time.Time `json:"occurred_at"`
+	Producer      string    `json:"producer"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	Data          T         `json:"data"`
+}
+
+// Metadata is supplied by the producer. ID should be stable across retries,
+// e.g. an outbox row ID; a random one is generated when empty.
+type Metadata struct {
+	ID            string
+	Producer      string
+	CorrelationID string
+	OccurredAt    time.Time
+}
+
+// header is the part of an envelope that can be read without knowing the payload type
+type header struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+}
+
+func NewEnvelope[T Contract](meta Metadata, data T) Envelope[T] {
+	if meta.ID == "" {
+		meta.ID = uuid.NewString()
+	}
+	if meta.OccurredAt.IsZero() {
+		meta.OccurredAt = time.Now().UTC()
+	}
+	return Envelope[T]{
+		ID:            meta.ID,
+		Type:          data.MessageType(),
+		Version:       data.MessageVersion(),
+		OccurredAt:    meta.OccurredAt,
+		Producer:      meta.Producer,
+		CorrelationID: meta.CorrelationID,
+		Data:          data,
+	}
+}
+
+// Decode parses a JSON body as an envelope of T, rejecting other types and versions
+func Decode[T Contract](body []byte) (Envelope[T], error) {
+	return Unmarshal[T](rabbitmq.ContentTypeJSON, body)
+}
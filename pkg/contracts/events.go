@@ -0,0 +1,55 @@
+package contracts
+
+import "time"
+
+// TicketType is a ticket tier announced with a new event. Price is a decimal string.
+type TicketType struct {
+	ID            string `json:"id,omitempty"`
+	Name          string `json:"name"`
+	Description   string `json:"description,omitempty"`
+	Price         string `json:"price"`
+	TotalQuantity int    `json:"total_quantity"`
+}
+
+// EventCreatedV1 is published by event-service when an event is created
+type EventCreatedV1 struct {
+	EventID     string       `json:"event_id"`
+	OrganizerID string       `json:"organizer_id"`
+	Title       string       `json:"event_title"`
+	StartDate   *time.Time   `json:"start_date,omitempty"`
+	TicketTypes []TicketType `json:"ticket_types,omitempty"`
+}
+
+func (EventCreatedV1) Exchange() string    { return ExchangeEvents }
+func (EventCreatedV1) MessageType() string { return "event.created" }
+func (EventCreatedV1) MessageVersion() int { return 1 }
+
+// EventCreatedPendingV1 announces an event whose tickets still need to be set up
+type EventCreatedPendingV1 struct {
+	EventID     string       `json:"event_id"`
+	OrganizerID string       `json:"organizer_id"`
+	Title       string       `json:"event_title"`
+	TicketTypes []TicketType `json:"ticket_types,omitempty"`
+}
+
+func (EventCreatedPendingV1) Exchange() string    { return ExchangeEvents }
+func (EventCreatedPendingV1) MessageType() string { return "event.created.pending" }
+func (EventCreatedPendingV1) MessageVersion() int { return 1 }
+
+// EventUpdatedV1 carries the fields that changed, keyed by their JSON name
+type EventUpdatedV1 struct {
+	EventID string         `json:"event_id"`
+	Updates map[string]any `json:"updates"`
+}
+
+func (EventUpdatedV1) Exchange() string    { return ExchangeEvents }
+func (EventUpdatedV1) MessageType() string { return "event.updated" }
+func (EventUpdatedV1) MessageVersion() int { return 1 }
+
+type EventDeletedV1 struct {
+	EventID string `json:"event_id"`
+}
+
+func (EventDeletedV1) Exchange() string    { return ExchangeEvents }
+func (EventDeletedV1) MessageType() string { return "event.deleted" }
+func (EventDeletedV1) MessageVersion() int { return 1 }
@@ -0,0 +1,15 @@
+package contracts
+
+// OrderCompletedV1 is published by ticket-service once an order is paid.
+// TotalAmount is a decimal string.
+type OrderCompletedV1 struct {
+	OrderID     string `json:"order_id"`
+	EventID     string `json:"event_id"`
+	OrganizerID string `json:"organizer_id"`
+	Quantity    int    `json:"quantity"`
+	TotalAmount string `json:"total_amount"`
+}
+
+func (OrderCompletedV1) Exchange() string    { return ExchangeTickets }
+func (OrderCompletedV1) MessageType() string { return "order.completed" }
+func (OrderCompletedV1) MessageVersion() int { return 1 }
@@ -0,0 +1,565 @@
+// Protobuf encoding of the message contracts in pkg/contracts. Field names
+// follow the JSON names; regenerate with `go generate ./pkg/contracts/...`
+// after editing.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: contracts.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Envelope mirrors contracts.Envelope. data holds the payload message named by
+// type and version, encoded on its own.
+type Envelope struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Version       int32                  `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	OccurredAt    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	Producer      string                 `protobuf:"bytes,5,opt,name=producer,proto3" json:"producer,omitempty"`
+	CorrelationId string                 `protobuf:"bytes,6,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	Data          []byte                 `protobuf:"bytes,7,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Envelope) Reset() {
+	*x = Envelope{}
+	mi := &file_contracts_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Envelope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Envelope) ProtoMessage() {}
+
+func (x *Envelope) ProtoReflect() protoreflect.Message {
+	mi := &file_contracts_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Envelope.ProtoReflect.Descriptor instead.
+func (*Envelope) Descriptor() ([]byte, []int) {
+	return file_contracts_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Envelope) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Envelope) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Envelope) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Envelope) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+func (x *Envelope) GetProducer() string {
+	if x != nil {
+		return x.Producer
+	}
+	return ""
+}
+
+func (x *Envelope) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationId
+	}
+	return ""
+}
+
+func (x *Envelope) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type TicketType struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Id          string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name        string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Description string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	// Decimal string, e.g. "25.00"
+	Price         string `protobuf:"bytes,4,opt,name=price,proto3" json:"price,omitempty"`
+	TotalQuantity int64  `protobuf:"varint,5,opt,name=total_quantity,json=totalQuantity,proto3" json:"total_quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TicketType) Reset() {
+	*x = TicketType{}
+	mi := &file_contracts_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TicketType) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TicketType) ProtoMessage() {}
+
+func (x *TicketType) ProtoReflect() protoreflect.Message {
+	mi := &file_contracts_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TicketType.ProtoReflect.Descriptor instead.
+func (*TicketType) Descriptor() ([]byte, []int) {
+	return file_contracts_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TicketType) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *TicketType) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *TicketType) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *TicketType) GetPrice() string {
+	if x != nil {
+		return x.Price
+	}
+	return ""
+}
+
+func (x *TicketType) GetTotalQuantity() int64 {
+	if x != nil {
+		return x.TotalQuantity
+	}
+	return 0
+}
+
+type EventCreatedV1 struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EventId       string                 `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	OrganizerId   string                 `protobuf:"bytes,2,opt,name=organizer_id,json=organizerId,proto3" json:"organizer_id,omitempty"`
+	EventTitle    string                 `protobuf:"bytes,3,opt,name=event_title,json=eventTitle,proto3" json:"event_title,omitempty"`
+	StartDate     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=start_date,json=startDate,proto3" json:"start_date,omitempty"`
+	TicketTypes   []*TicketType          `protobuf:"bytes,5,rep,name=ticket_types,json=ticketTypes,proto3" json:"ticket_types,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventCreatedV1) Reset() {
+	*x = EventCreatedV1{}
+	mi := &file_contracts_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventCreatedV1) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventCreatedV1) ProtoMessage() {}
+
+func (x *EventCreatedV1) ProtoReflect() protoreflect.Message {
+	mi := &file_contracts_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventCreatedV1.ProtoReflect.Descriptor instead.
+func (*EventCreatedV1) Descriptor() ([]byte, []int) {
+	return file_contracts_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *EventCreatedV1) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *EventCreatedV1) GetOrganizerId() string {
+	if x != nil {
+		return x.OrganizerId
+	}
+	return ""
+}
+
+func (x *EventCreatedV1) GetEventTitle() string {
+	if x != nil {
+		return x.EventTitle
+	}
+	return ""
+}
+
+func (x *EventCreatedV1) GetStartDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartDate
+	}
+	return nil
+}
+
+func (x *EventCreatedV1) GetTicketTypes() []*TicketType {
+	if x != nil {
+		return x.TicketTypes
+	}
+	return nil
+}
+
+type EventCreatedPendingV1 struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EventId       string                 `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	OrganizerId   string                 `protobuf:"bytes,2,opt,name=organizer_id,json=organizerId,proto3" json:"organizer_id,omitempty"`
+	EventTitle    string                 `protobuf:"bytes,3,opt,name=event_title,json=eventTitle,proto3" json:"event_title,omitempty"`
+	TicketTypes   []*TicketType          `protobuf:"bytes,4,rep,name=ticket_types,json=ticketTypes,proto3" json:"ticket_types,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventCreatedPendingV1) Reset() {
+	*x = EventCreatedPendingV1{}
+	mi := &file_contracts_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventCreatedPendingV1) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventCreatedPendingV1) ProtoMessage() {}
+
+func (x *EventCreatedPendingV1) ProtoReflect() protoreflect.Message {
+	mi := &file_contracts_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventCreatedPendingV1.ProtoReflect.Descriptor instead.
+func (*EventCreatedPendingV1) Descriptor() ([]byte, []int) {
+	return file_contracts_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *EventCreatedPendingV1) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *EventCreatedPendingV1) GetOrganizerId() string {
+	if x != nil {
+		return x.OrganizerId
+	}
+	return ""
+}
+
+func (x *EventCreatedPendingV1) GetEventTitle() string {
+	if x != nil {
+		return x.EventTitle
+	}
+	return ""
+}
+
+func (x *EventCreatedPendingV1) GetTicketTypes() []*TicketType {
+	if x != nil {
+		return x.TicketTypes
+	}
+	return nil
+}
+
+type EventDeletedV1 struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EventId       string                 `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventDeletedV1) Reset() {
+	*x = EventDeletedV1{}
+	mi := &file_contracts_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventDeletedV1) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventDeletedV1) ProtoMessage() {}
+
+func (x *EventDeletedV1) ProtoReflect() protoreflect.Message {
+	mi := &file_contracts_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventDeletedV1.ProtoReflect.Descriptor instead.
+func (*EventDeletedV1) Descriptor() ([]byte, []int) {
+	return file_contracts_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *EventDeletedV1) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+type OrderCompletedV1 struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	OrderId     string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	EventId     string                 `protobuf:"bytes,2,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	OrganizerId string                 `protobuf:"bytes,3,opt,name=organizer_id,json=organizerId,proto3" json:"organizer_id,omitempty"`
+	Quantity    int64                  `protobuf:"varint,4,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	// Decimal string, e.g. "50.00"
+	TotalAmount   string `protobuf:"bytes,5,opt,name=total_amount,json=totalAmount,proto3" json:"total_amount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderCompletedV1) Reset() {
+	*x = OrderCompletedV1{}
+	mi := &file_contracts_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderCompletedV1) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderCompletedV1) ProtoMessage() {}
+
+func (x *OrderCompletedV1) ProtoReflect() protoreflect.Message {
+	mi := &file_contracts_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderCompletedV1.ProtoReflect.Descriptor instead.
+func (*OrderCompletedV1) Descriptor() ([]byte, []int) {
+	return file_contracts_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *OrderCompletedV1) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *OrderCompletedV1) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *OrderCompletedV1) GetOrganizerId() string {
+	if x != nil {
+		return x.OrganizerId
+	}
+	return ""
+}
+
+func (x *OrderCompletedV1) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *OrderCompletedV1) GetTotalAmount() string {
+	if x != nil {
+		return x.TotalAmount
+	}
+	return ""
+}
+
+var File_contracts_proto protoreflect.FileDescriptor
+
+const file_contracts_proto_rawDesc = "" +
+	"\n" +
+	"\x0fcontracts.proto\x12\x15entritts.contracts.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xdc\x01\n" +
+	"\bEnvelope\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\x05R\aversion\x12;\n" +
+	"\voccurred_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAt\x12\x1a\n" +
+	"\bproducer\x18\x05 \x01(\tR\bproducer\x12%\n" +
+	"\x0ecorrelation_id\x18\x06 \x01(\tR\rcorrelationId\x12\x12\n" +
+	"\x04data\x18\a \x01(\fR\x04data\"\x8f\x01\n" +
+	"\n" +
+	"TicketType\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x14\n" +
+	"\x05price\x18\x04 \x01(\tR\x05price\x12%\n" +
+	"\x0etotal_quantity\x18\x05 \x01(\x03R\rtotalQuantity\"\xf0\x01\n" +
+	"\x0eEventCreatedV1\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId\x12!\n" +
+	"\forganizer_id\x18\x02 \x01(\tR\vorganizerId\x12\x1f\n" +
+	"\vevent_title\x18\x03 \x01(\tR\n" +
+	"eventTitle\x129\n" +
+	"\n" +
+	"start_date\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tstartDate\x12D\n" +
+	"\fticket_types\x18\x05 \x03(\v2!.entritts.contracts.v1.TicketTypeR\vticketTypes\"\xbc\x01\n" +
+	"\x15EventCreatedPendingV1\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId\x12!\n" +
+	"\forganizer_id\x18\x02 \x01(\tR\vorganizerId\x12\x1f\n" +
+	"\vevent_title\x18\x03 \x01(\tR\n" +
+	"eventTitle\x12D\n" +
+	"\fticket_types\x18\x04 \x03(\v2!.entritts.contracts.v1.TicketTypeR\vticketTypes\"+\n" +
+	"\x0eEventDeletedV1\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId\"\xaa\x01\n" +
+	"\x10OrderCompletedV1\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\x12\x19\n" +
+	"\bevent_id\x18\x02 \x01(\tR\aeventId\x12!\n" +
+	"\forganizer_id\x18\x03 \x01(\tR\vorganizerId\x12\x1a\n" +
+	"\bquantity\x18\x04 \x01(\x03R\bquantity\x12!\n" +
+	"\ftotal_amount\x18\x05 \x01(\tR\vtotalAmountB0Z.github.com/whotterre/entritts/pkg/contracts/pbb\x06proto3"
+
+var (
+	file_contracts_proto_rawDescOnce sync.Once
+	file_contracts_proto_rawDescData []byte
+)
+
+func file_contracts_proto_rawDescGZIP() []byte {
+	file_contracts_proto_rawDescOnce.Do(func() {
+		file_contracts_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_contracts_proto_rawDesc), len(file_contracts_proto_rawDesc)))
+	})
+	return file_contracts_proto_rawDescData
+}
+
+var file_contracts_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_contracts_proto_goTypes = []any{
+	(*Envelope)(nil),              // 0: entritts.contracts.v1.Envelope
+	(*TicketType)(nil),            // 1: entritts.contracts.v1.TicketType
+	(*EventCreatedV1)(nil),        // 2: entritts.contracts.v1.EventCreatedV1
+	(*EventCreatedPendingV1)(nil), // 3: entritts.contracts.v1.EventCreatedPendingV1
+	(*EventDeletedV1)(nil),        // 4: entritts.contracts.v1.EventDeletedV1
+	(*OrderCompletedV1)(nil),      // 5: entritts.contracts.v1.OrderCompletedV1
+	(*timestamppb.Timestamp)(nil), // 6: google.protobuf.Timestamp
+}
+var file_contracts_proto_depIdxs = []int32{
+	6, // 0: entritts.contracts.v1.Envelope.occurred_at:type_name -> google.protobuf.Timestamp
+	6, // 1: entritts.contracts.v1.EventCreatedV1.start_date:type_name -> google.protobuf.Timestamp
+	1, // 2: entritts.contracts.v1.EventCreatedV1.ticket_types:type_name -> entritts.contracts.v1.TicketType
+	1, // 3: entritts.contracts.v1.EventCreatedPendingV1.ticket_types:type_name -> entritts.contracts.v1.TicketType
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_contracts_proto_init() }
+func file_contracts_proto_init() {
+	if File_contracts_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_contracts_proto_rawDesc), len(file_contracts_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_contracts_proto_goTypes,
+		DependencyIndexes: file_contracts_proto_depIdxs,
+		MessageInfos:      file_contracts_proto_msgTypes,
+	}.Build()
+	File_contracts_proto = out.File
+	file_contracts_proto_goTypes = nil
+	file_contracts_proto_depIdxs = nil
+}
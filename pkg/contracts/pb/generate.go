@@ -0,0 +1,5 @@
+// Package pb holds the protobuf types generated from contracts.proto. Use the
+// conversions in pkg/contracts rather than these types directly.
+package pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative contracts.proto
@@ -0,0 +1,147 @@
+package contracts
+
+import (
+	"time"
+
+	"github.com/whotterre/entritts/pkg/contracts/pb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Conversions between the contracts and their generated protobuf types.
+// EventUpdatedV1 carries free-form updates and is only encoded as JSON.
+
+func (e EventCreatedV1) marshalProto() ([]byte, error) {
+	return proto.Marshal(&pb.EventCreatedV1{
+		EventId:     e.EventID,
+		OrganizerId: e.OrganizerID,
+		EventTitle:  e.Title,
+		StartDate:   timestampFrom(e.StartDate),
+		TicketTypes: ticketTypesToProto(e.TicketTypes),
+	})
+}
+
+func (e *EventCreatedV1) unmarshalProto(data []byte) error {
+	var m pb.EventCreatedV1
+	if err := proto.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*e = EventCreatedV1{
+		EventID:     m.GetEventId(),
+		OrganizerID: m.GetOrganizerId(),
+		Title:       m.GetEventTitle(),
+		StartDate:   timeFrom(m.GetStartDate()),
+		TicketTypes: ticketTypesFromProto(m.GetTicketTypes()),
+	}
+	return nil
+}
+
+func (e EventCreatedPendingV1) marshalProto() ([]byte, error) {
+	return proto.Marshal(&pb.EventCreatedPendingV1{
+		EventId:     e.EventID,
+		OrganizerId: e.OrganizerID,
+		EventTitle:  e.Title,
+		TicketTypes: ticketTypesToProto(e.TicketTypes),
+	})
+}
+
+func (e *EventCreatedPendingV1) unmarshalProto(data []byte) error {
+	var m pb.EventCreatedPendingV1
+	if err := proto.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*e = EventCreatedPendingV1{
+		EventID:     m.GetEventId(),
+		OrganizerID: m.GetOrganizerId(),
+		Title:       m.GetEventTitle(),
+		TicketTypes: ticketTypesFromProto(m.GetTicketTypes()),
+	}
+	return nil
+}
+
+func (e EventDeletedV1) marshalProto() ([]byte, error) {
+	return proto.Marshal(&pb.EventDeletedV1{EventId: e.EventID})
+}
+
+func (e *EventDeletedV1) unmarshalProto(data []byte) error {
+	var m pb.EventDeletedV1
+	if err := proto.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*e = EventDeletedV1{EventID: m.GetEventId()}
+	return nil
+}
+
+func (o OrderCompletedV1) marshalProto() ([]byte, error) {
+	return proto.Marshal(&pb.OrderCompletedV1{
+		OrderId:     o.OrderID,
+		EventId:     o.EventID,
+		OrganizerId: o.OrganizerID,
+		Quantity:    int64(o.Quantity),
+		TotalAmount: o.TotalAmount,
+	})
+}
+
+func (o *OrderCompletedV1) unmarshalProto(data []byte) error {
+	var m pb.OrderCompletedV1
+	if err := proto.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*o = OrderCompletedV1{
+		OrderID:     m.GetOrderId(),
+		EventID:     m.GetEventId(),
+		OrganizerID: m.GetOrganizerId(),
+		Quantity:    int(m.GetQuantity()),
+		TotalAmount: m.GetTotalAmount(),
+	}
+	return nil
+}
+
+func ticketTypesToProto(types []TicketType) []*pb.TicketType {
+	if types == nil {
+		return nil
+	}
+	out := make([]*pb.TicketType, len(types))
+	for i, t := range types {
+		out[i] = &pb.TicketType{
+			Id:            t.ID,
+			Name:          t.Name,
+			Description:   t.Description,
+			Price:         t.Price,
+			TotalQuantity: int64(t.TotalQuantity),
+		}
+	}
+	return out
+}
+
+func ticketTypesFromProto(types []*pb.TicketType) []TicketType {
+	if len(types) == 0 {
+		return nil
+	}
+	out := make([]TicketType, len(types))
+	for i, t := range types {
+		out[i] = TicketType{
+			ID:            t.GetId(),
+			Name:          t.GetName(),
+			Description:   t.GetDescription(),
+			Price:         t.GetPrice(),
+			TotalQuantity: int(t.GetTotalQuantity()),
+		}
+	}
+	return out
+}
+
+func timestampFrom(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func timeFrom(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
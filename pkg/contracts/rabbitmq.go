@@ -0,0 +1,85 @@
+package contracts
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/whotterre/entritts/pkg/rabbitmq"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Publish wraps data in an envelope and publishes it to the contract's exchange
+// using its type as the routing key. It is encoded with the publisher's
+// preferred content type when the contract supports it, and JSON otherwise.
+func Publish[T Contract](ctx context.Context, publisher *rabbitmq.Publisher, meta Metadata, data T) error {
+	env := NewEnvelope(meta, data)
+	body, contentType, err := Marshal(env, publisher.PreferredContentType())
+	if err != nil {
+		return err
+	}
+
+	return publisher.PublishContext(ctx, rabbitmq.Event{
+		Exchange:      data.Exchange(),
+		RoutingKey:    env.Type,
+		MessageID:     env.ID,
+		Type:          env.Type,
+		CorrelationID: env.CorrelationID,
+		ContentType:   contentType,
+		Body:          body,
+	})
+}
+
+// Handler handles one version of one contract
+type Handler[T Contract] func(ctx context.Context, env Envelope[T], delivery amqp.Delivery) error
+
+// Router dispatches deliveries to typed handlers by envelope type and version,
+// decoding JSON and protobuf bodies alike.
+// Messages it has no handler for are rejected as permanent failures.
+type Router struct {
+	handlers map[string]map[int]rabbitmq.MessageHandler
+}
+
+func NewRouter() *Router {
+	return &Router{handlers: make(map[string]map[int]rabbitmq.MessageHandler)}
+}
+
+// On registers handler for T's type and version
+func On[T Contract](r *Router, handler Handler[T]) {
+	var zero T
+	versions, ok := r.handlers[zero.MessageType()]
+	if !ok {
+		versions = make(map[int]rabbitmq.MessageHandler)
+		r.handlers[zero.MessageType()] = versions
+	}
+	versions[zero.MessageVersion()] = func(ctx context.Context, delivery amqp.Delivery) error {
+		env, err := Unmarshal[T](rabbitmq.MediaType(delivery), delivery.Body)
+		if err != nil {
+			return fmt.Errorf("%w: %v", rabbitmq.ErrPermanentFailure, err)
+		}
+		return handler(ctx, env, delivery)
+	}
+}
+
+// Handle is a rabbitmq.MessageHandler
+func (r *Router) Handle(ctx context.Context, delivery amqp.Delivery) error {
+	h, err := peekHeader(rabbitmq.MediaType(delivery), delivery.Body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", rabbitmq.ErrPermanentFailure, err)
+	}
+
+	versions, ok := r.handlers[h.Type]
+	if !ok {
+		return fmt.Errorf("%w: %w %q", rabbitmq.ErrPermanentFailure, ErrUnknownType, h.Type)
+	}
+	handler, ok := versions[h.Version]
+	if !ok {
+		return fmt.Errorf("%w: %w %s v%d", rabbitmq.ErrPermanentFailure, ErrUnknownVersion, h.Type, h.Version)
+	}
+	return handler(ctx, delivery)
+}
+
+// Subscribe consumes opts.QueueName, dispatching through router
+func Subscribe(ctx context.Context, consumer *rabbitmq.Consumer, opts rabbitmq.ConsumeOptions, router *Router) error {
+	return consumer.Consume(ctx, opts, router.Handle)
+}
@@ -0,0 +1,109 @@
+package contracts
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/whotterre/entritts/pkg/rabbitmq"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// registry holds the schema of every contract, keyed by "<type>.v<version>"
+var registry = map[string]*Schema{}
+
+// transcoders convert protobuf envelopes to JSON, keyed like registry
+var transcoders = map[string]func(body []byte) ([]byte, error){}
+
+func init() {
+	register[EventCreatedV1]()
+	register[EventCreatedPendingV1]()
+	register[EventUpdatedV1]()
+	register[EventDeletedV1]()
+	register[OrderCompletedV1]()
+}
+
+// register adds T to the registry. New contracts and versions must be listed in init.
+func register[T Contract]() {
+	schema := SchemaOf[T]()
+	if _, ok := registry[schema.ID]; ok {
+		panic(fmt.Sprintf("contracts: %s registered twice", schema.ID))
+	}
+	registry[schema.ID] = schema
+	if SupportsProtobuf[T]() {
+		transcoders[schema.ID] = transcode[T]
+	}
+}
+
+// SchemaFor returns the schema of a message type and version
+func SchemaFor(messageType string, version int) (*Schema, error) {
+	if schema, ok := registry[schemaKey(messageType, version)]; ok {
+		return schema, nil
+	}
+	for key := range registry {
+		if registry[key].Title == messageType {
+			return nil, fmt.Errorf("%w %s v%d", ErrUnknownVersion, messageType, version)
+		}
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownType, messageType)
+}
+
+// Schemas returns every registered schema ordered by ID
+func Schemas() []*Schema {
+	schemas := make([]*Schema, 0, len(registry))
+	for _, schema := range registry {
+		schemas = append(schemas, schema)
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].ID < schemas[j].ID })
+	return schemas
+}
+
+// ValidateMessage checks an encoded JSON envelope against the schema of its type and version
+func ValidateMessage(body []byte) error {
+	return ValidateContent(rabbitmq.ContentTypeJSON, body)
+}
+
+// ValidateContent is ValidateMessage for a body in any supported encoding.
+// Protobuf envelopes are converted to JSON and checked against the same schema.
+func ValidateContent(contentType string, body []byte) error {
+	h, err := peekHeader(contentType, body)
+	if err != nil {
+		return err
+	}
+	schema, err := SchemaFor(h.Type, h.Version)
+	if err != nil {
+		return err
+	}
+	if body, err = ToJSON(contentType, body); err != nil {
+		return err
+	}
+	return schema.Validate(body)
+}
+
+// ToJSON returns the JSON encoding of an envelope in any supported encoding,
+// for code that inspects message bodies without decoding them into a contract
+func ToJSON(contentType string, body []byte) ([]byte, error) {
+	if contentType != rabbitmq.ContentTypeProtobuf {
+		return body, nil
+	}
+
+	h, err := peekHeader(contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	toJSON, ok := transcoders[schemaKey(h.Type, h.Version)]
+	if !ok {
+		return nil, fmt.Errorf("%w %q for %s v%d", rabbitmq.ErrUnsupportedContentType, contentType, h.Type, h.Version)
+	}
+	return toJSON(body)
+}
+
+// ValidateEvent can be used as rabbitmq.Publisher.Validate
+func ValidateEvent(event rabbitmq.Event) error {
+	return ValidateContent(event.ContentType, event.Body)
+}
+
+// ValidateDelivery can be used as rabbitmq.ConsumeOptions.Validate
+func ValidateDelivery(delivery amqp.Delivery) error {
+	return ValidateContent(rabbitmq.MediaType(delivery), delivery.Body)
+}
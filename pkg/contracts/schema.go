@@ -0,0 +1,137 @@
+package contracts
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is the subset of JSON Schema generated from contract structs
+type Schema struct {
+	Draft                string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 SchemaType         `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Const                any                `json:"const,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// SchemaType is a JSON Schema type, or a list of types such as ["string", "null"]
+type SchemaType []string
+
+func (t SchemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *SchemaType) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = SchemaType{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+func (t SchemaType) allows(name string) bool {
+	if len(t) == 0 {
+		return true
+	}
+	for _, v := range t {
+		if v == name || (v == "number" && name == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaOf generates the schema of T's envelope, with type and version pinned
+func SchemaOf[T Contract]() *Schema {
+	var zero T
+	schema := generate(reflect.TypeOf(Envelope[T]{}))
+	schema.Draft = schemaDraft
+	schema.ID = schemaKey(zero.MessageType(), zero.MessageVersion())
+	schema.Title = zero.MessageType()
+	schema.Properties["type"].Const = zero.MessageType()
+	schema.Properties["version"].Const = zero.MessageVersion()
+	return schema
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+func generate(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: SchemaType{"string"}, Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := generate(t.Elem())
+		if len(schema.Type) > 0 {
+			schema.Type = append(schema.Type, "null")
+		}
+		return schema
+	case reflect.String:
+		return &Schema{Type: SchemaType{"string"}}
+	case reflect.Bool:
+		return &Schema{Type: SchemaType{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: SchemaType{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: SchemaType{"number"}}
+	case reflect.Slice, reflect.Array:
+		// nil slices encode as null
+		return &Schema{Type: SchemaType{"array", "null"}, Items: generate(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: SchemaType{"object", "null"}, AdditionalProperties: generate(t.Elem())}
+	case reflect.Struct:
+		return generateStruct(t)
+	}
+	// Interfaces accept anything
+	return &Schema{}
+}
+
+func generateStruct(t reflect.Type) *Schema {
+	schema := &Schema{Type: SchemaType{"object"}, Properties: make(map[string]*Schema)}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = generate(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+func schemaKey(messageType string, version int) string {
+	return messageType + ".v" + strconv.Itoa(version)
+}
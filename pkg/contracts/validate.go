@@ -0,0 +1,114 @@
+package contracts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ValidationError lists every place a document differs from its schema
+type ValidationError struct {
+	Schema   string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Schema, strings.Join(e.Problems, "; "))
+}
+
+// Validate checks an encoded JSON document against the schema
+func (s *Schema) Validate(body []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return err
+	}
+
+	var problems []string
+	s.validate("$", doc, &problems)
+	if len(problems) > 0 {
+		return &ValidationError{Schema: s.ID, Problems: problems}
+	}
+	return nil
+}
+
+func (s *Schema) validate(path string, value any, problems *[]string) {
+	kind := jsonKind(value)
+	if !s.Type.allows(kind) {
+		*problems = append(*problems, fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(s.Type, " or "), kind))
+		return
+	}
+	if s.Const != nil && !sameConst(s.Const, value) {
+		*problems = append(*problems, fmt.Sprintf("%s: must be %v", path, s.Const))
+	}
+
+	switch v := value.(type) {
+	case string:
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+				*problems = append(*problems, fmt.Sprintf("%s: not a date-time", path))
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, problems)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s.%s: required", path, name))
+			}
+		}
+
+		// Sorted so problems are reported in a stable order
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			// Unknown properties are allowed so older consumers tolerate new fields
+			if prop, ok := s.Properties[name]; ok {
+				prop.validate(path+"."+name, v[name], problems)
+			} else if s.AdditionalProperties != nil {
+				s.AdditionalProperties.validate(path+"."+name, v[name], problems)
+			}
+		}
+	}
+}
+
+func jsonKind(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func sameConst(want, got any) bool {
+	if n, ok := got.(json.Number); ok {
+		return n.String() == fmt.Sprint(want)
+	}
+	return reflect.DeepEqual(want, got)
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -10,10 +11,15 @@ import (
 	"gorm.io/gorm/logger"
 )
 
+// DSN returns the connection string for cfg, for clients that connect outside GORM
+func (cfg DBConfig) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode)
+}
+
 // NewPostgresConnection creates a new PostgreSQL connection with GORM
 func NewPostgresConnection(cfg DBConfig) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode)
+	dsn := cfg.DSN()
 
 	// GORM configuration
 	gormConfig := &gorm.Config{
@@ -57,12 +63,17 @@ func NewPostgresConnection(cfg DBConfig) (*gorm.DB, error) {
 
 // HealthCheck verifies the database connection is still alive
 func HealthCheck(db *gorm.DB) error {
+	return HealthCheckContext(context.Background(), db)
+}
+
+// HealthCheckContext is HealthCheck with a context bounding the ping
+func HealthCheckContext(ctx context.Context, db *gorm.DB) error {
 	sqlDB, err := db.DB()
 	if err != nil {
 		return err
 	}
 
-	return sqlDB.Ping()
+	return sqlDB.PingContext(ctx)
 }
 
 // CloseDatabaseConnection gracefully closes the database connection
@@ -0,0 +1,161 @@
+// Package health serves liveness and readiness endpoints. Liveness only says
+// the process is up; readiness runs every registered check and reports each
+// dependency's status and latency, answering 503 when any of them is down.
+package health
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Statuses
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// DefaultTimeout bounds a readiness check when none is configured
+const DefaultTimeout = 2 * time.Second
+
+// Check reports whether a dependency is usable
+type Check func(ctx context.Context) error
+
+// Result is the outcome of one check
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the body of a health response
+type Report struct {
+	Status  string            `json:"status"`
+	Service string            `json:"service"`
+	Checks  map[string]Result `json:"checks,omitempty"`
+}
+
+// Checker runs a service's readiness checks
+type Checker struct {
+	service string
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	checks map[string]Check
+}
+
+func NewChecker(service string, timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Checker{
+		service: service,
+		timeout: timeout,
+		checks:  make(map[string]Check),
+	}
+}
+
+// Add registers a readiness check under name, replacing any with that name
+func (h *Checker) Add(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check
+}
+
+// Run runs every check at once, each bounded by the checker's timeout
+func (h *Checker) Run(ctx context.Context) Report {
+	return h.RunOnly(ctx)
+}
+
+// RunOnly is Run limited to the named checks; no names runs every check
+func (h *Checker) RunOnly(ctx context.Context, names ...string) Report {
+	h.mu.RLock()
+	checks := make(map[string]Check, len(h.checks))
+	for name, check := range h.checks {
+		if len(names) == 0 || slices.Contains(names, name) {
+			checks[name] = check
+		}
+	}
+	h.mu.RUnlock()
+
+	report := Report{
+		Status:  StatusUp,
+		Service: h.service,
+		Checks:  make(map[string]Result, len(checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := h.runCheck(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+func (h *Checker) runCheck(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() { errc <- check(ctx) }()
+
+	// Checks that ignore ctx still can't hold up the response
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{
+		Status:    StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Live answers 200 while the process can serve requests at all
+func (h *Checker) Live(c *fiber.Ctx) error {
+	return c.JSON(Report{Status: StatusUp, Service: h.service})
+}
+
+// Ready answers 200 when every check passes and 503 otherwise, with the
+// result of each check
+func (h *Checker) Ready(c *fiber.Ctx) error {
+	return h.respond(c, h.Run(c.UserContext()))
+}
+
+// ReadyFor answers like Ready using only the named checks, for probes such as
+// the gateway's that should only react to what serving requests needs
+func (h *Checker) ReadyFor(names ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return h.respond(c, h.RunOnly(c.UserContext(), names...))
+	}
+}
+
+func (h *Checker) respond(c *fiber.Ctx, report Report) error {
+	if report.Status != StatusUp {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(report)
+	}
+	return c.JSON(report)
+}
@@ -0,0 +1,73 @@
+package inbox
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrMissingMessageID = errors.New("message has no ID")
+
+// ProcessedMessage records that a consumer has already handled a message. The
+// composite key lets several consumers share the same table.
+type ProcessedMessage struct {
+	MessageID   string    `gorm:"type:varchar(255);primaryKey" json:"message_id"`
+	Consumer    string    `gorm:"type:varchar(255);primaryKey" json:"consumer"`
+	ProcessedAt time.Time `gorm:"autoCreateTime;index" json:"processed_at"`
+}
+
+// Store deduplicates message handling using a Postgres table
+type Store struct {
+	db *gorm.DB
+}
+
+func NewStore(db *gorm.DB) *Store {
+	return &Store{db: db}
+}
+
+// Process runs fn in a transaction that also records the message as processed
+// by consumer. If the message was already recorded fn is skipped and false is
+// returned. When fn fails nothing is recorded, so a redelivery runs it again.
+//
+// fn must do its writes through the given tx for them to be committed together
+// with the inbox record.
+func (s *Store) Process(ctx context.Context, consumer, messageID string, fn func(tx *gorm.DB) error) (bool, error) {
+	if messageID == "" {
+		return false, ErrMissingMessageID
+	}
+
+	processed := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// A concurrent delivery of the same message blocks here until the
+		// first transaction commits or rolls back
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&ProcessedMessage{
+			MessageID: messageID,
+			Consumer:  consumer,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		processed = true
+		return nil
+	})
+	return processed, err
+}
+
+// Purge removes records older than retention. Redeliveries after that point are
+// treated as new messages.
+func (s *Store) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	result := s.db.WithContext(ctx).
+		Where("processed_at < ?", time.Now().Add(-retention)).
+		Delete(&ProcessedMessage{})
+	return result.RowsAffected, result.Error
+}
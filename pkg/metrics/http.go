@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// unmatchedRoute labels requests no route handled, keeping arbitrary paths
+// out of the label values
+const unmatchedRoute = "unmatched"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests handled, by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time taken to handle HTTP requests, by method, route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// Middleware records the count and latency of requests by the route pattern
+// that handled them, such as /api/v1/users/:id
+func Middleware() fiber.Handler {
+	return MiddlewareWithRoute(func(c *fiber.Ctx) string {
+		return c.Route().Path
+	})
+}
+
+// MiddlewareWithRoute is Middleware labelling each request with route(c),
+// called once the request has been handled. An empty label counts the request
+// as unmatched.
+func MiddlewareWithRoute(route func(*fiber.Ctx) string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		routeLabel := route(c)
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+			// Fiber answers requests no route matched with ErrNotFound
+			if status == fiber.StatusNotFound {
+				routeLabel = unmatchedRoute
+			}
+		}
+		if routeLabel == "" {
+			routeLabel = unmatchedRoute
+		}
+
+		labels := prometheus.Labels{
+			"method": c.Method(),
+			"route":  routeLabel,
+			"status": strconv.Itoa(status),
+		}
+		httpRequests.With(labels).Inc()
+		httpDuration.With(labels).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
@@ -0,0 +1,15 @@
+// Package metrics exposes Prometheus metrics for the services. Everything is
+// registered with the default registry, which Handler serves alongside the Go
+// runtime and process collectors.
+package metrics
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Handler serves the metrics in the Prometheus text format, for /metrics
+func Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.Handler())
+}
@@ -0,0 +1,13 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// TicketsSold counts tickets in completed orders. Reservations are held by the
+// ticket service, which doesn't export metrics yet.
+var TicketsSold = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "tickets_sold_total",
+	Help: "Tickets sold in completed orders.",
+})
@@ -0,0 +1,461 @@
+package outbox
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pglogrepl"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/whotterre/entritts/pkg/rabbitmq"
+)
+
+var (
+	ErrInvalidIdentifier = errors.New("slot and publication names must be lower case identifiers")
+	errNotOutboxRow      = errors.New("change is not an outbox row")
+)
+
+// cdcPublishTimeout bounds a single publish so a stuck broker can't stall
+// status updates past the server's wal_sender_timeout
+const cdcPublishTimeout = 30 * time.Second
+
+var identifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// CDCConfig tunes a CDCRelay. Zero fields take defaults.
+type CDCConfig struct {
+	// DSN of the outbox database; the relay opens a replication connection to it
+	DSN string
+	// SlotName is the logical replication slot, default "outbox_relay". Only one
+	// relay can stream from a slot at a time.
+	SlotName string
+	// Publication is created for inserts into outbox_messages, default "outbox_messages_pub"
+	Publication string
+	// StatusInterval is how often the confirmed LSN is reported, default 10s
+	StatusInterval time.Duration
+	// MaxAttempts before a message is quarantined instead of holding up the stream
+	MaxAttempts int
+	// Retention is how long rows are kept after insert; the relay reads the
+	// WAL, so deleting rows never loses messages. Zero disables cleanup.
+	Retention       time.Duration
+	CleanupInterval time.Duration
+}
+
+// CDCRelay publishes outbox rows as they are inserted by streaming the WAL
+// through a pgoutput replication slot, instead of polling the table. The
+// slot's confirmed LSN records progress, and it is only advanced past a
+// transaction once all of its messages are confirmed by the broker. Rows are
+// also marked published as they go out, so Backfill can find the ones the
+// stream never carried. Use it instead of Relay, not alongside it.
+type CDCRelay struct {
+	store     *Store
+	publisher *rabbitmq.Publisher
+	cfg       CDCConfig
+	logger    *log.Logger
+
+	relations map[uint32]*pglogrepl.RelationMessage
+	// attempts counts failed publishes per message across reconnects
+	attempts map[uuid.UUID]int
+
+	mu        sync.RWMutex
+	confirmed pglogrepl.LSN
+	// pendingSince is the commit time of the oldest transaction streamed but
+	// not yet confirmed; zero when everything received has been confirmed
+	pendingSince time.Time
+}
+
+func NewCDCRelay(store *Store, publisher *rabbitmq.Publisher, cfg CDCConfig, logger *log.Logger) *CDCRelay {
+	if cfg.SlotName == "" {
+		cfg.SlotName = "outbox_relay"
+	}
+	if cfg.Publication == "" {
+		cfg.Publication = "outbox_messages_pub"
+	}
+	if cfg.StatusInterval <= 0 {
+		cfg.StatusInterval = 10 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultConfig().MaxAttempts
+	}
+	if cfg.CleanupInterval <= 0 {
+		cfg.CleanupInterval = DefaultConfig().CleanupInterval
+	}
+
+	return &CDCRelay{
+		store:     store,
+		publisher: publisher,
+		cfg:       cfg,
+		logger:    logger,
+		relations: make(map[uint32]*pglogrepl.RelationMessage),
+		attempts:  make(map[uuid.UUID]int),
+	}
+}
+
+// ConfirmedLSN is the position up to which every message has been published
+func (r *CDCRelay) ConfirmedLSN() pglogrepl.LSN {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.confirmed
+}
+
+// PendingSince is when the oldest message the relay has received but not
+// confirmed was committed. Rows are never marked published, so this stands in
+// for the table's backlog.
+func (r *CDCRelay) PendingSince() (time.Time, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.pendingSince, !r.pendingSince.IsZero()
+}
+
+// Setup creates the publication and replication slot if they don't exist. The
+// database needs wal_level=logical. Rows inserted before the slot was created
+// are not in its stream; Backfill publishes them.
+func (r *CDCRelay) Setup(ctx context.Context) error {
+	if !identifierPattern.MatchString(r.cfg.SlotName) || !identifierPattern.MatchString(r.cfg.Publication) {
+		return ErrInvalidIdentifier
+	}
+
+	db := r.store.db.WithContext(ctx)
+	err := db.Exec(fmt.Sprintf(`
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_publication WHERE pubname = '%[1]s') THEN
+		CREATE PUBLICATION %[1]s FOR TABLE outbox_messages WITH (publish = 'insert');
+	END IF;
+END $$;`, r.cfg.Publication)).Error
+	if err != nil {
+		return fmt.Errorf("create publication: %w", err)
+	}
+
+	err = db.Exec(`SELECT pg_create_logical_replication_slot(?, 'pgoutput')
+		WHERE NOT EXISTS (SELECT 1 FROM pg_replication_slots WHERE slot_name = ?)`,
+		r.cfg.SlotName, r.cfg.SlotName).Error
+	if err != nil {
+		return fmt.Errorf("create replication slot: %w", err)
+	}
+	return nil
+}
+
+// Backfill publishes every unpublished, unquarantined row in order, whatever
+// its lease or retry delay: rows written before the slot existed, left behind
+// by the polling relay, requeued, or streamed but not marked published before
+// a crash. It retries until the backlog is empty and only returns early when
+// ctx is cancelled. Call it after Setup and before Run; rows that are also in
+// the stream are published twice and dropped by consumers' inboxes.
+func (r *CDCRelay) Backfill(ctx context.Context) error {
+	var (
+		after     int64
+		published int
+		delay     = time.Second
+	)
+	for {
+		messages, err := r.store.Unpublished(ctx, after, DefaultConfig().BatchSize)
+		if err == nil && len(messages) == 0 {
+			if published > 0 {
+				r.logger.Printf("Backfilled %d outbox messages", published)
+			}
+			return nil
+		}
+
+		for _, msg := range messages {
+			if err = r.publishRow(ctx, msg); err != nil {
+				break
+			}
+			after = msg.Sequence
+			published++
+			delay = time.Second
+		}
+		if err == nil {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		r.logger.Printf("Outbox backfill failed, retrying in %v: %v", delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay = min(delay*2, 30*time.Second)
+	}
+}
+
+// Run streams and publishes outbox inserts until ctx is cancelled,
+// reconnecting after errors. After a reconnect the server resends everything
+// after the confirmed LSN, so some messages may be published twice.
+func (r *CDCRelay) Run(ctx context.Context) {
+	go r.cleanup(ctx)
+
+	r.logger.Printf("Starting outbox CDC relay on slot %s", r.cfg.SlotName)
+
+	delay := time.Second
+	for ctx.Err() == nil {
+		err := r.stream(ctx, func() { delay = time.Second })
+		if ctx.Err() != nil {
+			break
+		}
+
+		r.logger.Printf("Outbox CDC stream stopped, retrying in %v: %v", delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+		delay = min(delay*2, 30*time.Second)
+	}
+	r.logger.Printf("Stopping outbox CDC relay")
+}
+
+func (r *CDCRelay) stream(ctx context.Context, connected func()) error {
+	conn, err := pgconn.Connect(ctx, r.cfg.DSN+" replication=database")
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	// Start from the slot's confirmed position
+	err = pglogrepl.StartReplication(ctx, conn, r.cfg.SlotName, 0, pglogrepl.StartReplicationOptions{
+		PluginArgs: []string{
+			"proto_version '1'",
+			"publication_names '" + r.cfg.Publication + "'",
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("start replication: %w", err)
+	}
+	connected()
+
+	var (
+		// position is what can be confirmed: the end of the last transaction
+		// whose messages were all published
+		position      = r.ConfirmedLSN()
+		inTransaction bool
+		// commitTime is when the transaction being streamed was committed
+		commitTime time.Time
+		nextStatus = time.Now().Add(r.cfg.StatusInterval)
+	)
+
+	for {
+		if time.Now().After(nextStatus) {
+			// Everything before the current transaction is covered by position
+			var pending time.Time
+			if inTransaction {
+				pending = commitTime
+			}
+			if err := r.confirm(ctx, conn, position, pending); err != nil {
+				return err
+			}
+			nextStatus = time.Now().Add(r.cfg.StatusInterval)
+		}
+
+		receiveCtx, cancel := context.WithDeadline(ctx, nextStatus)
+		raw, err := conn.ReceiveMessage(receiveCtx)
+		cancel()
+		if err != nil {
+			if pgconn.Timeout(err) && ctx.Err() == nil {
+				continue
+			}
+			return err
+		}
+
+		switch msg := raw.(type) {
+		case *pgproto3.ErrorResponse:
+			return fmt.Errorf("replication error: %s", msg.Message)
+		case *pgproto3.CopyData:
+			if len(msg.Data) == 0 {
+				continue
+			}
+			switch msg.Data[0] {
+			case pglogrepl.PrimaryKeepaliveMessageByteID:
+				keepalive, err := pglogrepl.ParsePrimaryKeepaliveMessage(msg.Data[1:])
+				if err != nil {
+					return err
+				}
+				// Nothing is pending between transactions, so the slot can move
+				// past WAL that held no outbox inserts
+				if !inTransaction && keepalive.ServerWALEnd > position {
+					position = keepalive.ServerWALEnd
+				}
+				if keepalive.ReplyRequested {
+					nextStatus = time.Time{}
+				}
+
+			case pglogrepl.XLogDataByteID:
+				xld, err := pglogrepl.ParseXLogData(msg.Data[1:])
+				if err != nil {
+					return err
+				}
+				logical, err := pglogrepl.Parse(xld.WALData)
+				if err != nil {
+					return err
+				}
+
+				switch change := logical.(type) {
+				case *pglogrepl.BeginMessage:
+					inTransaction = true
+					commitTime = change.CommitTime
+					r.markPending(commitTime)
+				case *pglogrepl.RelationMessage:
+					r.relations[change.RelationID] = change
+				case *pglogrepl.InsertMessage:
+					if err := r.handleInsert(ctx, change); err != nil {
+						return err
+					}
+				case *pglogrepl.CommitMessage:
+					inTransaction = false
+					position = change.TransactionEndLSN
+				}
+			}
+		}
+	}
+}
+
+// confirm reports position to the server, letting it discard older WAL.
+// pending is the commit time of the transaction still being streamed, if any.
+func (r *CDCRelay) confirm(ctx context.Context, conn *pgconn.PgConn, position pglogrepl.LSN, pending time.Time) error {
+	err := pglogrepl.SendStandbyStatusUpdate(ctx, conn, pglogrepl.StandbyStatusUpdate{WALWritePosition: position})
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.confirmed = position
+	r.pendingSince = pending
+	r.mu.Unlock()
+	return nil
+}
+
+// markPending records a transaction's commit time unless an older one is
+// still unconfirmed, e.g. when it is replayed after a reconnect
+func (r *CDCRelay) markPending(commitTime time.Time) {
+	r.mu.Lock()
+	if r.pendingSince.IsZero() {
+		r.pendingSince = commitTime
+	}
+	r.mu.Unlock()
+}
+
+// handleInsert publishes an inserted row. An error ends the stream so the
+// transaction is replayed after reconnecting.
+func (r *CDCRelay) handleInsert(ctx context.Context, change *pglogrepl.InsertMessage) error {
+	msg, err := r.decode(change)
+	if errors.Is(err, errNotOutboxRow) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return r.publishRow(ctx, msg)
+}
+
+// publishRow publishes a message and marks it published. A message that keeps
+// failing is quarantined so it no longer holds up the ones after it.
+func (r *CDCRelay) publishRow(ctx context.Context, msg Message) error {
+	publishCtx, cancel := context.WithTimeout(msg.traceContext(ctx), cdcPublishTimeout)
+	defer cancel()
+	err := r.publisher.PublishContext(publishCtx, msg.event())
+	if err == nil {
+		delete(r.attempts, msg.ID)
+		if err := r.store.MarkPublishedByID(ctx, msg.ID); err != nil {
+			// The message went out; the next Backfill sends it again and
+			// consumers' inboxes drop it
+			r.logger.Printf("Failed to mark outbox message %s as published: %v", msg.ID, err)
+		}
+		return nil
+	}
+
+	r.attempts[msg.ID]++
+	attempts := r.attempts[msg.ID]
+	if !errors.Is(err, rabbitmq.ErrInvalidMessage) && attempts < r.cfg.MaxAttempts {
+		return fmt.Errorf("publish %s: %w", msg.ID, err)
+	}
+
+	r.logger.Printf("Quarantining outbox message %s (%s) after %d attempts: %v", msg.ID, msg.RoutingKey, attempts, err)
+	delete(r.attempts, msg.ID)
+	if qerr := r.store.Quarantine(ctx, msg.ID, attempts, err.Error()); qerr != nil {
+		return fmt.Errorf("quarantine %s: %w", msg.ID, qerr)
+	}
+	return nil
+}
+
+// decode builds a Message from the text encoded columns of an inserted row
+func (r *CDCRelay) decode(change *pglogrepl.InsertMessage) (Message, error) {
+	var msg Message
+	relation, ok := r.relations[change.RelationID]
+	if !ok {
+		return msg, fmt.Errorf("insert for unknown relation %d", change.RelationID)
+	}
+	if relation.RelationName != msg.TableName() {
+		return msg, errNotOutboxRow
+	}
+
+	for i, column := range change.Tuple.Columns {
+		if i >= len(relation.Columns) || column.DataType != pglogrepl.TupleDataTypeText {
+			continue
+		}
+		value := string(column.Data)
+
+		var err error
+		switch relation.Columns[i].Name {
+		case "id":
+			msg.ID, err = uuid.Parse(value)
+		case "sequence":
+			msg.Sequence, err = strconv.ParseInt(value, 10, 64)
+		case "aggregate_id":
+			msg.AggregateID = value
+		case "exchange":
+			msg.Exchange = value
+		case "routing_key":
+			msg.RoutingKey = value
+		case "message_type":
+			msg.MessageType = value
+		case "content_type":
+			msg.ContentType = value
+		case "correlation_id":
+			msg.CorrelationID = value
+		case "trace_parent":
+			msg.TraceParent = value
+		case "trace_state":
+			msg.TraceState = value
+		case "body":
+			// bytea's text form is \x followed by hex
+			msg.Body, err = hex.DecodeString(strings.TrimPrefix(value, `\x`))
+		}
+		if err != nil {
+			return msg, fmt.Errorf("column %s: %w", relation.Columns[i].Name, err)
+		}
+	}
+	return msg, nil
+}
+
+func (r *CDCRelay) cleanup(ctx context.Context) {
+	if r.cfg.Retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.cfg.CleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			purged, err := r.store.PurgeCreatedBefore(ctx, r.cfg.Retention)
+			if err != nil {
+				r.logger.Printf("Failed to purge outbox messages: %v", err)
+			} else if purged > 0 {
+				r.logger.Printf("Purged %d outbox messages", purged)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
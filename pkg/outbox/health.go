@@ -0,0 +1,42 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// LagCheck returns a health check that fails while the oldest pending
+// message has waited longer than maxLag to be published
+func (s *Store) LagCheck(maxLag time.Duration) func(context.Context) error {
+	return func(ctx context.Context) error {
+		backlog, err := s.Backlog(ctx)
+		if err != nil {
+			return err
+		}
+		if backlog.OldestPending != nil {
+			if lag := time.Since(*backlog.OldestPending); lag > maxLag {
+				return fmt.Errorf("%w: oldest of %d pending messages is %v old",
+					ErrPublishLagging, backlog.Pending, lag.Round(time.Second))
+			}
+		}
+		return nil
+	}
+}
+
+// LagCheck returns a health check that fails while the oldest message the
+// relay has received has waited longer than maxLag to be confirmed. Use it
+// instead of Store.LagCheck in CDC mode, where rows stay unpublished.
+func (r *CDCRelay) LagCheck(maxLag time.Duration) func(context.Context) error {
+	return func(ctx context.Context) error {
+		since, ok := r.PendingSince()
+		if !ok {
+			return nil
+		}
+		if lag := time.Since(since); lag > maxLag {
+			return fmt.Errorf("%w: oldest unconfirmed transaction is %v old",
+				ErrPublishLagging, lag.Round(time.Second))
+		}
+		return nil
+	}
+}
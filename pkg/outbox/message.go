@@ -0,0 +1,83 @@
+// Package outbox implements the transactional outbox pattern: services write
+// messages to a table in the same transaction as their own changes, and a
+// Relay publishes them to RabbitMQ afterwards.
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whotterre/entritts/pkg/rabbitmq"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Message is an encoded message waiting to be published. Relays claim rows by
+// setting LockedBy and LockedUntil; a row whose lease has expired can be
+// claimed again by any relay.
+type Message struct {
+	// ID is also the published message ID, so consumers can deduplicate
+	ID uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	// Sequence orders rows, and so publishes, within an aggregate
+	Sequence    int64  `gorm:"type:bigserial;autoIncrement;not null;index:idx_outbox_messages_aggregate,priority:2" json:"sequence"`
+	AggregateID string `gorm:"type:varchar(255);not null;index:idx_outbox_messages_aggregate,priority:1" json:"aggregate_id"`
+	Exchange    string `gorm:"type:varchar(255);not null" json:"exchange"`
+	RoutingKey  string `gorm:"type:varchar(255);not null" json:"routing_key"`
+	MessageType string `gorm:"type:varchar(255)" json:"message_type"`
+	ContentType string `gorm:"type:varchar(100);not null" json:"content_type"`
+	// CorrelationID is the ID of the request that wrote the message
+	CorrelationID string `gorm:"type:varchar(255)" json:"correlation_id,omitempty"`
+	// TraceParent and TraceState are the W3C trace context of the writer, so
+	// the publish joins the trace of the request that wrote the message
+	TraceParent string `gorm:"type:varchar(255)" json:"trace_parent,omitempty"`
+	TraceState  string `gorm:"type:varchar(512)" json:"trace_state,omitempty"`
+	Body        []byte `gorm:"type:bytea;not null" json:"-"`
+
+	Published   bool       `gorm:"not null;default:false;index:idx_outbox_messages_pending,priority:1" json:"published"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	PublishedAt *time.Time `gorm:"index" json:"published_at,omitempty"`
+
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	LastError   string     `gorm:"type:text" json:"last_error,omitempty"`
+	LockedBy    string     `gorm:"type:varchar(255)" json:"locked_by,omitempty"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	// Quarantined rows failed too often and are skipped until requeued
+	Quarantined   bool       `gorm:"not null;default:false;index:idx_outbox_messages_pending,priority:2" json:"quarantined"`
+	QuarantinedAt *time.Time `json:"quarantined_at,omitempty"`
+}
+
+func (Message) TableName() string {
+	return "outbox_messages"
+}
+
+// event is the message as relays publish it
+func (m Message) event() rabbitmq.Event {
+	return rabbitmq.Event{
+		Exchange:      m.Exchange,
+		RoutingKey:    m.RoutingKey,
+		MessageID:     m.ID.String(),
+		Type:          m.MessageType,
+		CorrelationID: m.CorrelationID,
+		ContentType:   m.ContentType,
+		Body:          m.Body,
+	}
+}
+
+// setTraceContext records the trace context carried by ctx, if any
+func (m *Message) setTraceContext(ctx context.Context) {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	m.TraceParent = carrier.Get("traceparent")
+	m.TraceState = carrier.Get("tracestate")
+}
+
+// traceContext returns ctx continuing the trace the message was written in
+func (m Message) traceContext(ctx context.Context) context.Context {
+	if m.TraceParent == "" {
+		return ctx
+	}
+	return propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{
+		"traceparent": m.TraceParent,
+		"tracestate":  m.TraceState,
+	})
+}
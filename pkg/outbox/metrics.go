@@ -0,0 +1,92 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scrapeTimeout bounds the backlog query run on every scrape
+const scrapeTimeout = 5 * time.Second
+
+var (
+	pendingDesc = prometheus.NewDesc("outbox_pending_messages",
+		"Outbox messages waiting to be published, excluding quarantined ones.", nil, nil)
+	oldestPendingDesc = prometheus.NewDesc("outbox_oldest_pending_age_seconds",
+		"Age of the oldest outbox message waiting to be published, 0 when there is none.", nil, nil)
+	quarantinedDesc = prometheus.NewDesc("outbox_quarantined_messages",
+		"Outbox messages quarantined after failing too often.", nil, nil)
+)
+
+// Backlog summarises the unpublished rows of the outbox
+type Backlog struct {
+	Pending     int64
+	Quarantined int64
+	// OldestPending is when the oldest pending row was written, or nil
+	OldestPending *time.Time
+}
+
+// Backlog counts the rows still to be published
+func (s *Store) Backlog(ctx context.Context) (Backlog, error) {
+	var backlog Backlog
+	err := s.db.WithContext(ctx).Raw(`
+SELECT COUNT(*) FILTER (WHERE NOT quarantined) AS pending,
+	COUNT(*) FILTER (WHERE quarantined) AS quarantined,
+	MIN(created_at) FILTER (WHERE NOT quarantined) AS oldest_pending
+FROM outbox_messages
+WHERE published = false`).Scan(&backlog).Error
+	return backlog, err
+}
+
+// backlogCollector reports the outbox backlog at scrape time
+type backlogCollector struct {
+	store *Store
+	// cdc, when set, tracks what is pending instead of the table: rows it
+	// publishes are never marked published
+	cdc *CDCRelay
+}
+
+// RegisterMetrics exports the size and age of the store's backlog, for use
+// with Relay
+func RegisterMetrics(s *Store) error {
+	return prometheus.Register(backlogCollector{store: s})
+}
+
+// RegisterCDCMetrics exports the age of the oldest message r has yet to
+// confirm and the number of quarantined rows. The number of pending messages
+// isn't known until they are streamed, so it isn't reported.
+func RegisterCDCMetrics(r *CDCRelay) error {
+	return prometheus.Register(backlogCollector{store: r.store, cdc: r})
+}
+
+func (c backlogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pendingDesc
+	ch <- oldestPendingDesc
+	ch <- quarantinedDesc
+}
+
+func (c backlogCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+
+	backlog, err := c.store.Backlog(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(quarantinedDesc, err)
+		return
+	}
+
+	var oldestAge float64
+	if c.cdc != nil {
+		if since, ok := c.cdc.PendingSince(); ok {
+			oldestAge = time.Since(since).Seconds()
+		}
+	} else {
+		if backlog.OldestPending != nil {
+			oldestAge = time.Since(*backlog.OldestPending).Seconds()
+		}
+		ch <- prometheus.MustNewConstMetric(pendingDesc, prometheus.GaugeValue, float64(backlog.Pending))
+	}
+	ch <- prometheus.MustNewConstMetric(oldestPendingDesc, prometheus.GaugeValue, oldestAge)
+	ch <- prometheus.MustNewConstMetric(quarantinedDesc, prometheus.GaugeValue, float64(backlog.Quarantined))
+}
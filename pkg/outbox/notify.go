@@ -0,0 +1,87 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// NotifyChannel is the Postgres channel the outbox trigger notifies on
+const NotifyChannel = "outbox_messages"
+
+// notifyTrigger notifies once per inserting statement rather than per row.
+// Postgres delivers the notification when the inserting transaction commits,
+// so a relay woken by it always sees the new rows.
+const notifyTrigger = `
+CREATE OR REPLACE FUNCTION outbox_messages_notify() RETURNS trigger AS $$
+BEGIN
+	PERFORM pg_notify('` + NotifyChannel + `', '');
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS outbox_messages_notify ON outbox_messages;
+CREATE TRIGGER outbox_messages_notify
+	AFTER INSERT ON outbox_messages
+	FOR EACH STATEMENT EXECUTE FUNCTION outbox_messages_notify();`
+
+// InstallNotifyTrigger makes inserts into the outbox NOTIFY NotifyChannel. Run
+// it after migrating Message.
+func InstallNotifyTrigger(db *gorm.DB) error {
+	return db.Exec(notifyTrigger).Error
+}
+
+// listen holds a dedicated connection LISTENing on NotifyChannel and signals
+// wake for every notification, reconnecting until ctx is cancelled. wake is
+// also signalled after each (re)connect, in case notifications were missed
+// while disconnected.
+func listen(ctx context.Context, dsn string, wake chan<- struct{}, logger *log.Logger) {
+	delay := time.Second
+	for ctx.Err() == nil {
+		err := listenOnce(ctx, dsn, wake, func() { delay = time.Second })
+		if ctx.Err() != nil {
+			return
+		}
+
+		logger.Printf("Outbox listener disconnected, retrying in %v: %v", delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay = min(delay*2, 30*time.Second)
+	}
+}
+
+func listenOnce(ctx context.Context, dsn string, wake chan<- struct{}, connected func()) error {
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+NotifyChannel); err != nil {
+		return err
+	}
+	connected()
+	signal(wake)
+
+	for {
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return err
+		}
+		signal(wake)
+	}
+}
+
+// signal wakes the relay without blocking; a pending wake-up already covers
+// any rows this one is about
+func signal(wake chan<- struct{}) {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
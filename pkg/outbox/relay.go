@@ -0,0 +1,241 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whotterre/entritts/pkg/rabbitmq"
+)
+
+const (
+	maxLastErrorLength = 1024
+	maxRetryDelay      = 5 * time.Minute
+)
+
+// Config tunes a Relay. Zero fields take the defaults from DefaultConfig.
+type Config struct {
+	// PollInterval is how often the relay checks for messages. With ListenDSN
+	// set it is only a fallback for missed notifications.
+	PollInterval time.Duration
+	// ListenDSN, when set, is used to LISTEN for the notifications sent by
+	// InstallNotifyTrigger so messages are published as soon as they commit
+	ListenDSN string
+	BatchSize int
+	// Lease is how long a claimed row is reserved for one relay
+	Lease time.Duration
+	// MaxAttempts before a row is quarantined
+	MaxAttempts int
+	// Retention is how long published rows are kept; zero disables cleanup
+	Retention       time.Duration
+	CleanupInterval time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		PollInterval:    5 * time.Second,
+		BatchSize:       100,
+		Lease:           30 * time.Second,
+		MaxAttempts:     10,
+		Retention:       7 * 24 * time.Hour,
+		CleanupInterval: time.Hour,
+	}
+}
+
+// Relay publishes outbox messages. Several relays, e.g. one per replica, can
+// share a table: each claims its own rows.
+type Relay struct {
+	store     *Store
+	publisher *rabbitmq.Publisher
+	cfg       Config
+	logger    *log.Logger
+	// id identifies this relay's leases
+	id string
+}
+
+func NewRelay(store *Store, publisher *rabbitmq.Publisher, cfg Config, logger *log.Logger) *Relay {
+	defaults := DefaultConfig()
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaults.PollInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaults.BatchSize
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = defaults.Lease
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaults.MaxAttempts
+	}
+	if cfg.CleanupInterval <= 0 {
+		cfg.CleanupInterval = defaults.CleanupInterval
+	}
+
+	hostname, _ := os.Hostname()
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		cfg:       cfg,
+		logger:    logger,
+		id:        fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8]),
+	}
+}
+
+// ID identifies this relay in the locked_by column
+func (r *Relay) ID() string {
+	return r.id
+}
+
+// Run publishes pending messages when notified (with ListenDSN) or every
+// PollInterval, and purges old ones every CleanupInterval, until ctx is
+// cancelled. After a failure it also wakes when the earliest retry is due, so
+// retries aren't held up by a long PollInterval.
+func (r *Relay) Run(ctx context.Context) {
+	poll := time.NewTicker(r.cfg.PollInterval)
+	defer poll.Stop()
+	cleanup := time.NewTicker(r.cfg.CleanupInterval)
+	defer cleanup.Stop()
+	retry := time.NewTimer(0)
+	retry.Stop()
+	defer retry.Stop()
+
+	wake := make(chan struct{}, 1)
+	if r.cfg.ListenDSN != "" {
+		go listen(ctx, r.cfg.ListenDSN, wake, r.logger)
+	}
+
+	r.logger.Printf("Starting outbox relay %s (poll every %v, listening: %t)", r.id, r.cfg.PollInterval, r.cfg.ListenDSN != "")
+
+	for {
+		select {
+		case <-wake:
+			r.publishPending(ctx, retry)
+		case <-poll.C:
+			r.publishPending(ctx, retry)
+		case <-retry.C:
+			r.publishPending(ctx, retry)
+		case <-cleanup.C:
+			if r.cfg.Retention <= 0 {
+				continue
+			}
+			purged, err := r.store.Purge(ctx, r.cfg.Retention)
+			if err != nil {
+				r.logger.Printf("Failed to purge outbox messages: %v", err)
+			} else if purged > 0 {
+				r.logger.Printf("Purged %d published outbox messages", purged)
+			}
+		case <-ctx.Done():
+			r.logger.Printf("Stopping outbox relay %s", r.id)
+			return
+		}
+	}
+}
+
+// publishPending publishes what it can and, if anything failed, sets retry to
+// fire when the earliest failed row can be tried again
+func (r *Relay) publishPending(ctx context.Context, retry *time.Timer) {
+	failed, err := r.publishAll(ctx)
+	if err != nil && ctx.Err() == nil {
+		r.logger.Printf("Error publishing outbox messages: %v", err)
+	}
+	if !failed || ctx.Err() != nil {
+		return
+	}
+
+	wait, ok, err := r.store.NextRetry(ctx)
+	if err != nil {
+		r.logger.Printf("Failed to find the next outbox retry: %v", err)
+		return
+	}
+	if ok && wait < r.cfg.PollInterval {
+		retry.Reset(wait)
+	}
+}
+
+// PublishPending claims and publishes batches until nothing is left to claim.
+// Each batch holds at most one row per aggregate, so later rows of the same
+// aggregate are picked up by the following batch.
+func (r *Relay) PublishPending(ctx context.Context) error {
+	_, err := r.publishAll(ctx)
+	return err
+}
+
+// publishAll is PublishPending, also reporting whether any message failed
+func (r *Relay) publishAll(ctx context.Context) (bool, error) {
+	failed := false
+	for {
+		messages, err := r.store.Claim(ctx, r.id, r.cfg.BatchSize, r.cfg.Lease)
+		if err != nil {
+			return failed, err
+		}
+		if len(messages) == 0 {
+			return failed, nil
+		}
+
+		// Stop short of the lease so another relay never publishes a row this one still holds
+		deadline := time.Now().Add(r.cfg.Lease * 9 / 10)
+		published := r.publishBatch(ctx, messages, deadline)
+		if published < len(messages) {
+			failed = true
+		}
+		if published == 0 {
+			// Everything failed; leave the rows to their retry delay
+			return failed, nil
+		}
+	}
+}
+
+func (r *Relay) publishBatch(ctx context.Context, messages []Message, deadline time.Time) int {
+	published := 0
+	for i, msg := range messages {
+		if ctx.Err() != nil || time.Now().After(deadline) {
+			r.logger.Printf("Outbox lease nearly expired, leaving %d messages for the next batch", len(messages)-i)
+			break
+		}
+
+		publishCtx, cancel := context.WithDeadline(msg.traceContext(ctx), deadline)
+		err := r.publisher.PublishContext(publishCtx, msg.event())
+		cancel()
+		if err != nil {
+			r.recordFailure(ctx, msg, err)
+			continue
+		}
+
+		if err := r.store.MarkPublished(ctx, msg.ID, r.id); err != nil {
+			// The message went out; a redelivery will be dropped by consumers' inboxes
+			r.logger.Printf("Failed to mark outbox message %s as published: %v", msg.ID, err)
+		}
+		published++
+	}
+	return published
+}
+
+// recordFailure schedules a retry with exponential backoff, or quarantines the
+// row once it has used up its attempts
+func (r *Relay) recordFailure(ctx context.Context, msg Message, publishErr error) {
+	quarantine := errors.Is(publishErr, rabbitmq.ErrInvalidMessage) || msg.Attempts >= r.cfg.MaxAttempts
+
+	retryAfter := time.Duration(1<<min(max(msg.Attempts-1, 0), 16)) * time.Second
+	if retryAfter > maxRetryDelay {
+		retryAfter = maxRetryDelay
+	}
+
+	lastError := publishErr.Error()
+	if len(lastError) > maxLastErrorLength {
+		lastError = lastError[:maxLastErrorLength]
+	}
+
+	if quarantine {
+		r.logger.Printf("Quarantining outbox message %s (%s) after %d attempts: %v", msg.ID, msg.RoutingKey, msg.Attempts, publishErr)
+	} else {
+		r.logger.Printf("Failed to publish outbox message %s (%s, request %s), retrying in %v: %v", msg.ID, msg.RoutingKey, msg.CorrelationID, retryAfter, publishErr)
+	}
+
+	if err := r.store.RecordFailure(ctx, msg.ID, r.id, lastError, retryAfter, quarantine); err != nil {
+		r.logger.Printf("Failed to record outbox failure for %s: %v", msg.ID, err)
+	}
+}
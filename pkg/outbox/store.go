@@ -0,0 +1,249 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whotterre/entritts/pkg/contracts"
+	"github.com/whotterre/entritts/pkg/rabbitmq"
+	"github.com/whotterre/entritts/pkg/requestid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrMissingDestination = errors.New("outbox message has no exchange or routing key")
+	ErrPublishLagging     = errors.New("outbox messages are not being published")
+)
+
+// Store reads and writes the outbox table
+type Store struct {
+	db *gorm.DB
+	// ContentType is the encoding AddContract uses, defaulting to JSON.
+	// Contracts without a protobuf encoding are always stored as JSON.
+	ContentType string
+}
+
+func NewStore(db *gorm.DB) *Store {
+	return &Store{db: db}
+}
+
+// Add writes msg using tx, so it is only published if tx commits. The
+// correlation ID and trace context default to those carried by tx's context.
+func (s *Store) Add(tx *gorm.DB, msg *Message) error {
+	if msg.Exchange == "" || msg.RoutingKey == "" {
+		return ErrMissingDestination
+	}
+	if msg.ID == uuid.Nil {
+		msg.ID = uuid.New()
+	}
+	if msg.ContentType == "" {
+		msg.ContentType = rabbitmq.ContentTypeJSON
+	}
+	if msg.CorrelationID == "" {
+		msg.CorrelationID = requestid.FromContext(tx.Statement.Context)
+	}
+	if msg.TraceParent == "" {
+		msg.setTraceContext(tx.Statement.Context)
+	}
+	return tx.Create(msg).Error
+}
+
+// AddContract wraps data in an envelope and writes it using tx. The envelope ID
+// is the outbox row ID: meta.ID when it is a UUID, otherwise a new one. The
+// correlation ID defaults to the request ID carried by tx's context.
+func AddContract[T contracts.Contract](tx *gorm.DB, s *Store, aggregateID string, meta contracts.Metadata, data T) error {
+	if _, err := uuid.Parse(meta.ID); err != nil {
+		meta.ID = uuid.NewString()
+	}
+	if meta.CorrelationID == "" {
+		meta.CorrelationID = requestid.FromContext(tx.Statement.Context)
+	}
+	env := contracts.NewEnvelope(meta, data)
+	body, contentType, err := contracts.Marshal(env, s.ContentType)
+	if err != nil {
+		return err
+	}
+
+	return s.Add(tx, &Message{
+		ID:            uuid.MustParse(env.ID),
+		AggregateID:   aggregateID,
+		Exchange:      data.Exchange(),
+		RoutingKey:    env.Type,
+		MessageType:   env.Type,
+		ContentType:   contentType,
+		CorrelationID: env.CorrelationID,
+		Body:          body,
+	})
+}
+
+// claimQuery leases up to @limit pending rows to @relay. Only the oldest pending
+// row of each aggregate is eligible, so an aggregate's messages are published
+// one at a time in order even with several relays. SKIP LOCKED lets concurrent
+// relays claim disjoint batches without waiting on each other.
+const claimQuery = `
+UPDATE outbox_messages AS o
+SET locked_by = @relay,
+	locked_until = NOW() + make_interval(secs => @lease),
+	attempts = o.attempts + 1
+WHERE o.id IN (
+	SELECT c.id FROM outbox_messages AS c
+	WHERE c.published = false
+		AND c.quarantined = false
+		AND (c.locked_until IS NULL OR c.locked_until < NOW())
+		AND NOT EXISTS (
+			SELECT 1 FROM outbox_messages AS p
+			WHERE p.aggregate_id = c.aggregate_id
+				AND p.published = false
+				AND p.quarantined = false
+				AND p.sequence < c.sequence
+		)
+	ORDER BY c.sequence
+	LIMIT @limit
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING o.*`
+
+// Claim leases up to limit pending messages to relayID for lease
+func (s *Store) Claim(ctx context.Context, relayID string, limit int, lease time.Duration) ([]Message, error) {
+	var messages []Message
+	err := s.db.WithContext(ctx).Raw(claimQuery, map[string]interface{}{
+		"relay": relayID,
+		"lease": lease.Seconds(),
+		"limit": limit,
+	}).Scan(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// RETURNING doesn't preserve the subquery's order
+	sort.Slice(messages, func(i, j int) bool { return messages[i].Sequence < messages[j].Sequence })
+	return messages, nil
+}
+
+// MarkPublished releases a row relayID still holds the lease on
+func (s *Store) MarkPublished(ctx context.Context, id uuid.UUID, relayID string) error {
+	now := time.Now()
+	return s.db.WithContext(ctx).Model(&Message{}).
+		Where("id = ? AND locked_by = ?", id, relayID).
+		Updates(map[string]interface{}{
+			"published":    true,
+			"published_at": &now,
+			"last_error":   "",
+			"locked_by":    nil,
+			"locked_until": nil,
+		}).Error
+}
+
+// MarkPublishedByID marks a row published whoever holds its lease, for relays
+// that don't lease rows
+func (s *Store) MarkPublishedByID(ctx context.Context, id uuid.UUID) error {
+	now := time.Now()
+	return s.db.WithContext(ctx).Model(&Message{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"published":    true,
+			"published_at": &now,
+			"last_error":   "",
+			"locked_by":    nil,
+			"locked_until": nil,
+		}).Error
+}
+
+// Unpublished returns up to limit pending rows after the given sequence in
+// order, ignoring leases and retry delays
+func (s *Store) Unpublished(ctx context.Context, after int64, limit int) ([]Message, error) {
+	var messages []Message
+	err := s.db.WithContext(ctx).
+		Where("published = ? AND quarantined = ? AND sequence > ?", false, false, after).
+		Order("sequence").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+// NextRetry returns how long until the earliest lease on a pending row runs
+// out, which is when a failed row can be retried. ok is false when no row is
+// leased.
+func (s *Store) NextRetry(ctx context.Context) (wait time.Duration, ok bool, err error) {
+	var seconds *float64
+	err = s.db.WithContext(ctx).Raw(`
+SELECT EXTRACT(EPOCH FROM MIN(locked_until) - NOW())
+FROM outbox_messages
+WHERE published = false AND quarantined = false AND locked_until IS NOT NULL`).Scan(&seconds).Error
+	if err != nil || seconds == nil {
+		return 0, false, err
+	}
+	return max(time.Duration(*seconds*float64(time.Second)), 0), true, nil
+}
+
+// RecordFailure keeps the row leased until retryAfter has passed, or
+// quarantines it so relays stop picking it up
+func (s *Store) RecordFailure(ctx context.Context, id uuid.UUID, relayID, lastError string, retryAfter time.Duration, quarantine bool) error {
+	updates := map[string]interface{}{
+		"last_error": lastError,
+		// Leases are compared with the database clock, not the relay's
+		"locked_until": gorm.Expr("NOW() + make_interval(secs => ?)", retryAfter.Seconds()),
+	}
+	if quarantine {
+		now := time.Now()
+		updates["quarantined"] = true
+		updates["quarantined_at"] = &now
+		updates["locked_until"] = nil
+	}
+
+	return s.db.WithContext(ctx).Model(&Message{}).
+		Where("id = ? AND locked_by = ?", id, relayID).
+		Updates(updates).Error
+}
+
+// Quarantine flags a message that could not be published, for relays that
+// don't lease rows
+func (s *Store) Quarantine(ctx context.Context, id uuid.UUID, attempts int, lastError string) error {
+	if len(lastError) > maxLastErrorLength {
+		lastError = lastError[:maxLastErrorLength]
+	}
+	return s.db.WithContext(ctx).Model(&Message{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":       attempts,
+			"last_error":     lastError,
+			"quarantined":    true,
+			"quarantined_at": time.Now(),
+		}).Error
+}
+
+// Requeue releases quarantined messages with a fresh attempt budget. Relay
+// picks them up again; CDCRelay only on its next Backfill.
+func (s *Store) Requeue(ctx context.Context, ids ...uuid.UUID) (int64, error) {
+	result := s.db.WithContext(ctx).Model(&Message{}).
+		Where("id IN ? AND quarantined = ?", ids, true).
+		Updates(map[string]interface{}{
+			"quarantined":    false,
+			"quarantined_at": nil,
+			"attempts":       0,
+			"locked_by":      nil,
+			"locked_until":   nil,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// PurgeCreatedBefore deletes messages older than retention regardless of
+// Published, for relays that track progress outside the table. Quarantined
+// messages are kept.
+func (s *Store) PurgeCreatedBefore(ctx context.Context, retention time.Duration) (int64, error) {
+	result := s.db.WithContext(ctx).
+		Where("quarantined = ? AND created_at < ?", false, time.Now().Add(-retention)).
+		Delete(&Message{})
+	return result.RowsAffected, result.Error
+}
+
+// Purge deletes messages published longer than retention ago
+func (s *Store) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	result := s.db.WithContext(ctx).
+		Where("published = ? AND published_at < ?", true, time.Now().Add(-retention)).
+		Delete(&Message{})
+	return result.RowsAffected, result.Error
+}
@@ -1,14 +1,29 @@
 package rabbitmq
 
 import (
+	"context"
 	"log"
 	"time"
 
 	"github.com/rabbitmq/amqp091-go"
 )
 
-// Initialize RabbitMQ client
+const (
+	defaultReconnectInitialDelay = time.Second
+	defaultReconnectMaxDelay     = 30 * time.Second
+)
+
+// Initialize RabbitMQ client. The client supervises its connection: if the
+// broker goes away it reconnects with backoff, re-declares everything declared
+// through it and lets consumers resume.
 func NewClient(cfg Config, logger *log.Logger) (*Client, error) {
+	if cfg.ReconnectInitialDelay <= 0 {
+		cfg.ReconnectInitialDelay = defaultReconnectInitialDelay
+	}
+	if cfg.ReconnectMaxDelay <= 0 {
+		cfg.ReconnectMaxDelay = defaultReconnectMaxDelay
+	}
+
 	conn, err := connectWithRetry(cfg.URL, 5, 5*time.Second, logger)
 	if err != nil {
 		return nil, err
@@ -16,24 +31,75 @@ func NewClient(cfg Config, logger *log.Logger) (*Client, error) {
 
 	ch, err := conn.Channel()
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
 
+	c := &Client{
+		cfg:          cfg,
+		logger:       logger,
+		conn:         conn,
+		channel:      ch,
+		state:        StateConnected,
+		declarations: make(map[string]func(*amqp091.Channel) error),
+		changed:      make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	c.publishers = newChannelPool(c, cfg.PublisherChannels)
+	go c.supervise(conn, ch)
+
 	logger.Println("Successfully connected to RabbitMQ")
-	return &Client{Conn: conn, Channel: ch}, nil
+	return c, nil
+}
+
+// Channel returns the client's current channel. It changes after a reconnect,
+// so don't hold on to it.
+func (c *Client) Channel() *amqp091.Channel {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.channel
 }
 
-// EnsureExchange declares an exchange if it doesn't exist
+// Connection returns the client's current connection
+func (c *Client) Connection() *amqp091.Connection {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.conn
+}
+
+// State reports whether the client is currently connected
+func (c *Client) State() ConnectionState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.state
+}
+
+// OnStateChange registers a callback run whenever the connection state changes.
+// Callbacks run on the supervising goroutine and should return quickly.
+func (c *Client) OnStateChange(fn func(state ConnectionState, err error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, fn)
+}
+
+// EnsureExchange declares an exchange if it doesn't exist. Exchanges already
+// declared through the client are not declared again.
 func (c *Client) EnsureExchange(name, kind string) error {
-	return c.Channel.ExchangeDeclare(
-		name,  // exchange name
-		kind,  // exchange type: direct, fanout, topic, headers
-		true,  // durable
-		false, // auto-deleted
-		false, // internal
-		false, // no-wait
-		nil,   // arguments
-	)
+	key := "exchange:" + name
+	if c.isDeclared(key) {
+		return nil
+	}
+	return c.declare(key, func(ch *amqp091.Channel) error {
+		return ch.ExchangeDeclare(
+			name,  // exchange name
+			kind,  // exchange type: direct, fanout, topic, headers
+			true,  // durable
+			false, // auto-deleted
+			false, // internal
+			false, // no-wait
+			nil,   // arguments
+		)
+	})
 }
 
 // Attempts to connect with retries
@@ -57,36 +123,232 @@ func connectWithRetry(url string,
 }
 
 func (c *Client) Close(logger *log.Logger) {
-	if c.Channel != nil {
-		c.Channel.Close()
+	c.mu.Lock()
+	select {
+	case <-c.done:
+		c.mu.Unlock()
+		return
+	default:
+	}
+	close(c.done)
+	ch, conn := c.channel, c.conn
+	c.mu.Unlock()
+
+	c.setState(StateClosed, nil)
+	c.publishers.close()
+
+	if ch != nil {
+		ch.Close()
 		logger.Println("RabbitMQ channel closed")
 	}
 
-	if c.Conn != nil {
-		c.Conn.Close()
+	if conn != nil {
+		conn.Close()
 		logger.Println("RabbitMQ connection closed")
 	}
 }
 
 // Creates a new message queue if one doesn't exists
 func (c *Client) EnsureQueue(name string) (amqp091.Queue, error) {
-	return c.Channel.QueueDeclare(
-		name,
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
+	var queue amqp091.Queue
+	err := c.declare("queue:"+name, func(ch *amqp091.Channel) error {
+		var err error
+		queue, err = ch.QueueDeclare(
+			name,
+			true,
+			false,
+			false,
+			false,
+			nil,
+		)
+		return err
+	})
+	return queue, err
 }
 
 // Binds a queue to an exchange with a routing key
 func (c *Client) BindQueue(queueName, routingKey, exchangeName string) error {
-	return c.Channel.QueueBind(
-		queueName,
-		routingKey,
-		exchangeName,
-		false,
-		nil,
-	)
+	return c.declare("binding:"+exchangeName+":"+routingKey+":"+queueName, func(ch *amqp091.Channel) error {
+		return ch.QueueBind(
+			queueName,
+			routingKey,
+			exchangeName,
+			false,
+			nil,
+		)
+	})
+}
+
+// declare runs a declaration on the current channel and remembers it so it can
+// be replayed after a reconnect
+func (c *Client) declare(key string, fn func(*amqp091.Channel) error) error {
+	if err := fn(c.Channel()); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.declarations[key]; !ok {
+		c.declarationOrder = append(c.declarationOrder, key)
+	}
+	c.declarations[key] = fn
+	return nil
+}
+
+func (c *Client) isDeclared(key string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.declarations[key]
+	return ok
+}
+
+// supervise waits for the connection or channel to close and recovers unless
+// the client itself was closed
+func (c *Client) supervise(conn *amqp091.Connection, ch *amqp091.Channel) {
+	connClosed := conn.NotifyClose(make(chan *amqp091.Error, 1))
+	chClosed := ch.NotifyClose(make(chan *amqp091.Error, 1))
+
+	var reason *amqp091.Error
+	select {
+	case <-c.done:
+		return
+	case reason = <-connClosed:
+	case reason = <-chClosed:
+	}
+
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+
+	var err error
+	if reason != nil {
+		err = reason
+	}
+	c.logger.Printf("RabbitMQ connection lost: %v", err)
+	c.setState(StateReconnecting, err)
+	c.reconnect()
+}
+
+// reconnect retries with exponential backoff until a new channel is open and the
+// recorded topology has been declared on it
+func (c *Client) reconnect() {
+	delay := c.cfg.ReconnectInitialDelay
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-c.done:
+			return
+		case <-time.After(delay):
+		}
+
+		conn, ch, err := c.open()
+		if err == nil {
+			err = c.redeclare(ch)
+			if err != nil {
+				ch.Close()
+				// Don't leak a connection dialed for this attempt
+				if conn != c.Connection() {
+					conn.Close()
+				}
+			}
+		}
+		if err != nil {
+			c.logger.Printf("Failed to reconnect to RabbitMQ (attempt %d): %v", attempt, err)
+			delay = min(delay*2, c.cfg.ReconnectMaxDelay)
+			continue
+		}
+
+		c.mu.Lock()
+		select {
+		case <-c.done:
+			// Closed while reconnecting
+			c.mu.Unlock()
+			ch.Close()
+			if conn != c.conn {
+				conn.Close()
+			}
+			return
+		default:
+		}
+		c.conn, c.channel = conn, ch
+		c.mu.Unlock()
+
+		c.logger.Printf("Reconnected to RabbitMQ after %d attempt(s)", attempt)
+		c.setState(StateConnected, nil)
+		go c.supervise(conn, ch)
+		return
+	}
+}
+
+// open reuses the current connection when only the channel was closed,
+// otherwise it dials a new one
+func (c *Client) open() (*amqp091.Connection, *amqp091.Channel, error) {
+	conn := c.Connection()
+	if conn == nil || conn.IsClosed() {
+		var err error
+		conn, err = amqp091.Dial(c.cfg.URL)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, ch, nil
+}
+
+func (c *Client) redeclare(ch *amqp091.Channel) error {
+	c.mu.RLock()
+	declarations := make([]func(*amqp091.Channel) error, 0, len(c.declarationOrder))
+	for _, key := range c.declarationOrder {
+		declarations = append(declarations, c.declarations[key])
+	}
+	c.mu.RUnlock()
+
+	for _, declare := range declarations {
+		if err := declare(ch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) setState(state ConnectionState, err error) {
+	c.mu.Lock()
+	c.state = state
+	close(c.changed)
+	c.changed = make(chan struct{})
+	listeners := append([]func(ConnectionState, error){}, c.listeners...)
+	c.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(state, err)
+	}
+}
+
+// waitForChannel blocks until the client is connected on a channel other than
+// stale, e.g. so a consumer can resume after its channel was closed
+func (c *Client) waitForChannel(ctx context.Context, stale *amqp091.Channel) (*amqp091.Channel, error) {
+	for {
+		c.mu.RLock()
+		state, ch, changed := c.state, c.channel, c.changed
+		c.mu.RUnlock()
+
+		if state == StateClosed {
+			return nil, ErrClientClosed
+		}
+		if state == StateConnected && ch != stale && !ch.IsClosed() {
+			return ch, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+	}
 }
@@ -2,15 +2,25 @@ package rabbitmq
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
 	"log"
+	"sync"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/whotterre/entritts/pkg/requestid"
 )
 
 // Consumer handles message consumption from RabbitMQ
 type Consumer struct {
 	Client *Client
+
+	mu            sync.Mutex
+	subscriptions []*subscription
 }
 
 // NewConsumer creates a new consumer from a client
@@ -18,9 +28,13 @@ func NewConsumer(client *Client) *Consumer {
 	return &Consumer{Client: client}
 }
 
-// MessageHandler defines the function signature for processing messages
+// MessageHandler defines the function signature for processing messages.
+// Handlers must not ack or reject the delivery themselves; the consumer does so
+// based on the returned error.
 type MessageHandler func(ctx context.Context, delivery amqp.Delivery) error
 
+const defaultHandlerTimeout = 30 * time.Second
+
 // ConsumeOptions provides configuration for message consumption
 type ConsumeOptions struct {
 	QueueName     string
@@ -32,23 +46,144 @@ type ConsumeOptions struct {
 	Args          amqp.Table
 	PrefetchCount int
 	PrefetchSize  int
+	// Retry enables delayed redelivery and a dead-letter queue for failed
+	// messages. When nil failed messages are requeued immediately.
+	Retry *RetryPolicy
+	// Workers is the number of handlers running at once, defaulting to
+	// PrefetchCount (or 1)
+	Workers int
+	// OrderingKey, when set, routes deliveries with the same key to the same
+	// worker so they are handled one at a time in arrival order
+	OrderingKey func(delivery amqp.Delivery) string
+	// HandlerTimeout bounds a single handler call, default 30s
+	HandlerTimeout time.Duration
+	// Validate, when set, runs before the handler. Deliveries it rejects are
+	// treated as permanent failures and dead-lettered without retries.
+	Validate func(delivery amqp.Delivery) error
+	// Accept lists the content types the handler understands. Deliveries in
+	// any other encoding are dead-lettered; empty accepts everything.
+	Accept []string
+}
+
+// subscription is one running Consume call
+type subscription struct {
+	opts    ConsumeOptions
+	handler MessageHandler
+
+	// cancel stops dispatching; cancelHandlers aborts handlers still running
+	ctx            context.Context
+	cancel         context.CancelFunc
+	handlerCtx     context.Context
+	cancelHandlers context.CancelFunc
+
+	queues  []chan amqp.Delivery
+	workers sync.WaitGroup
+	done    chan struct{}
+
+	mu      sync.Mutex
+	channel *amqp.Channel
 }
 
-// Consume starts consuming messages from a queue
+// Consume starts consuming messages from a queue. Deliveries are handled by a
+// fixed pool of workers; use Stop to drain them on shutdown.
 func (c *Consumer) Consume(ctx context.Context, opts ConsumeOptions, handler MessageHandler) error {
+	if opts.Retry != nil && !opts.AutoAck {
+		if err := c.Client.DeclareRetryTopology(opts.QueueName, *opts.Retry); err != nil {
+			log.Printf("Failed to declare retry topology for %s: %v", opts.QueueName, err)
+			return ErrConsumeFailed
+		}
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = max(opts.PrefetchCount, 1)
+	}
+	if opts.HandlerTimeout <= 0 {
+		opts.HandlerTimeout = defaultHandlerTimeout
+	}
+
+	ch := c.Client.Channel()
+	deliveries, err := c.startConsuming(ch, opts)
+	if err != nil {
+		log.Printf("Failed to start consumer: %v", err)
+		return ErrConsumeFailed
+	}
+
+	sub := &subscription{
+		opts:    opts,
+		handler: handler,
+		done:    make(chan struct{}),
+		channel: ch,
+	}
+	sub.ctx, sub.cancel = context.WithCancel(ctx)
+	// In-flight handlers outlive ctx so they can finish during a graceful stop
+	sub.handlerCtx, sub.cancelHandlers = context.WithCancel(context.WithoutCancel(ctx))
+
+	// Without ordering all workers share one queue; with it each worker owns one
+	queueCount := 1
+	if opts.OrderingKey != nil {
+		queueCount = opts.Workers
+	}
+	sub.queues = make([]chan amqp.Delivery, queueCount)
+	for i := range sub.queues {
+		sub.queues[i] = make(chan amqp.Delivery)
+	}
+	for i := range opts.Workers {
+		sub.workers.Add(1)
+		go c.work(sub, sub.queues[i%queueCount])
+	}
+
+	c.mu.Lock()
+	c.subscriptions = append(c.subscriptions, sub)
+	c.mu.Unlock()
+
+	log.Printf("Started consumer %s on queue %s with %d worker(s)", opts.ConsumerTag, opts.QueueName, opts.Workers)
+
+	// Process messages in a goroutine
+	go c.processMessages(sub, deliveries)
+
+	return nil
+}
+
+// Stop stops receiving new deliveries and waits for in-flight handlers to
+// finish. If ctx expires first the remaining handlers are cancelled and ctx's
+// error is returned; their unacked messages are redelivered once the client's
+// channel closes. Close the client after Stop returns.
+func (c *Consumer) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	subs := c.subscriptions
+	c.subscriptions = nil
+	c.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.cancel()
+	}
+
+	for _, sub := range subs {
+		select {
+		case <-sub.done:
+		case <-ctx.Done():
+			for _, sub := range subs {
+				sub.cancelHandlers()
+			}
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// startConsuming sets QoS and starts a consumer on the given channel
+func (c *Consumer) startConsuming(ch *amqp.Channel, opts ConsumeOptions) (<-chan amqp.Delivery, error) {
 	// Set QoS (Quality of Service) to control message prefetching
-	err := c.Client.Channel.Qos(
+	err := ch.Qos(
 		opts.PrefetchCount, // prefetch count
 		opts.PrefetchSize,  // prefetch size
 		false,              // global
 	)
 	if err != nil {
-		log.Printf("Failed to set QoS: %v", err)
-		return ErrConsumeFailed
+		return nil, err
 	}
 
 	// Start consuming messages
-	deliveries, err := c.Client.Channel.Consume(
+	return ch.Consume(
 		opts.QueueName,   // queue
 		opts.ConsumerTag, // consumer tag
 		opts.AutoAck,     // auto-ack
@@ -57,56 +192,162 @@ func (c *Consumer) Consume(ctx context.Context, opts ConsumeOptions, handler Mes
 		opts.NoWait,      // no-wait
 		opts.Args,        // args
 	)
-	if err != nil {
-		log.Printf("Failed to start consumer: %v", err)
-		return ErrConsumeFailed
-	}
-
-	log.Printf("Started consumer %s on queue %s", opts.ConsumerTag, opts.QueueName)
-
-	// Process messages in a goroutine
-	go c.processMessages(ctx, deliveries, handler, opts)
-
-	return nil
 }
 
-// processMessages handles incoming messages and routes them to the handler
-func (c *Consumer) processMessages(ctx context.Context, deliveries <-chan amqp.Delivery, handler MessageHandler, opts ConsumeOptions) {
+// processMessages hands incoming messages to the workers. Handing off blocks
+// while the target worker is busy, which together with prefetch gives
+// backpressure.
+func (c *Consumer) processMessages(sub *subscription, deliveries <-chan amqp.Delivery) {
+	defer c.finish(sub, &deliveries)
+
+	opts := sub.opts
 	for {
 		select {
-		case <-ctx.Done():
-			log.Printf("Consumer %s stopped by context", opts.ConsumerTag)
+		case <-sub.ctx.Done():
+			log.Printf("Consumer %s stopped", opts.ConsumerTag)
 			return
 
 		case delivery, ok := <-deliveries:
 			if !ok {
-				log.Printf("Delivery channel closed for consumer %s", opts.ConsumerTag)
+				log.Printf("Delivery channel closed for consumer %s, waiting for reconnect", opts.ConsumerTag)
+				if deliveries, ok = c.resume(sub); !ok {
+					return
+				}
+				continue
+			}
+
+			queue := sub.queues[0]
+			if opts.OrderingKey != nil {
+				queue = sub.queues[workerFor(opts.OrderingKey(delivery), len(sub.queues))]
+			}
+
+			select {
+			case queue <- delivery:
+			case <-sub.ctx.Done():
+				if !opts.AutoAck {
+					delivery.Nack(false, true)
+				}
+				log.Printf("Consumer %s stopped", opts.ConsumerTag)
 				return
 			}
+		}
+	}
+}
+
+// finish cancels the broker-side consumer, returns prefetched deliveries to the
+// queue and waits for the workers to finish
+func (c *Consumer) finish(sub *subscription, deliveries *<-chan amqp.Delivery) {
+	sub.mu.Lock()
+	ch := sub.channel
+	sub.mu.Unlock()
+	if ch != nil && !ch.IsClosed() {
+		ch.Cancel(sub.opts.ConsumerTag, false)
+	}
 
-			// Process the message in a goroutine to handle concurrent processing
-			go c.handleMessage(ctx, delivery, handler, opts)
+	if *deliveries != nil && !sub.opts.AutoAck {
+	drain:
+		for {
+			select {
+			case delivery, ok := <-*deliveries:
+				if !ok {
+					break drain
+				}
+				delivery.Nack(false, true)
+			default:
+				break drain
+			}
 		}
 	}
+
+	for _, queue := range sub.queues {
+		close(queue)
+	}
+	sub.workers.Wait()
+	sub.cancelHandlers()
+	close(sub.done)
+}
+
+func (c *Consumer) work(sub *subscription, queue <-chan amqp.Delivery) {
+	defer sub.workers.Done()
+	for delivery := range queue {
+		c.handleMessage(sub.handlerCtx, delivery, sub.handler, sub.opts)
+	}
+}
+
+// resume restarts consumption once the client has recovered its channel.
+// Unacked deliveries from the old channel are redelivered by the broker.
+func (c *Consumer) resume(sub *subscription) (<-chan amqp.Delivery, bool) {
+	sub.mu.Lock()
+	stale := sub.channel
+	sub.mu.Unlock()
+
+	for {
+		ch, err := c.Client.waitForChannel(sub.ctx, stale)
+		if err != nil {
+			log.Printf("Consumer %s stopped: %v", sub.opts.ConsumerTag, err)
+			return nil, false
+		}
+
+		deliveries, err := c.startConsuming(ch, sub.opts)
+		if err == nil {
+			sub.mu.Lock()
+			sub.channel = ch
+			sub.mu.Unlock()
+			log.Printf("Resumed consumer %s on queue %s", sub.opts.ConsumerTag, sub.opts.QueueName)
+			return deliveries, true
+		}
+
+		log.Printf("Failed to resume consumer %s: %v", sub.opts.ConsumerTag, err)
+		stale = ch
+	}
+}
+
+func workerFor(key string, workers int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(workers))
 }
 
 // handleMessage processes a single message with error handling and retry logic
 func (c *Consumer) handleMessage(ctx context.Context, delivery amqp.Delivery, handler MessageHandler, opts ConsumeOptions) {
 	start := time.Now()
 
-	// Create a context with timeout for this message processing
-	msgCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	// Create a context with timeout for this message processing, carrying the
+	// ID of the request that caused the message
+	msgCtx, cancel := context.WithTimeout(ctx, opts.HandlerTimeout)
 	defer cancel()
+	requestID := RequestID(delivery)
+	msgCtx = requestid.WithID(msgCtx, requestID)
+	msgCtx, span := startConsumeSpan(msgCtx, delivery, opts.QueueName)
 
-	err := handler(msgCtx, delivery)
+	var err error
+	defer func() { endSpan(span, err) }()
+	if !accepts(opts.Accept, delivery) {
+		err = fmt.Errorf("%w: %w %q", ErrPermanentFailure, ErrUnsupportedContentType, delivery.ContentType)
+	}
+	if err == nil && opts.Validate != nil {
+		if verr := opts.Validate(delivery); verr != nil {
+			err = fmt.Errorf("%w: %w: %v", ErrPermanentFailure, ErrInvalidMessage, verr)
+		}
+	}
+	if err == nil {
+		handlerStart := time.Now()
+		err = handler(msgCtx, delivery)
+		handlerDuration.WithLabelValues(opts.QueueName).Observe(time.Since(handlerStart).Seconds())
+	}
+	messagesConsumed.WithLabelValues(opts.QueueName, statusLabel(err)).Inc()
 	if err != nil {
-		log.Printf("Message processing failed: %v (MessageID: %s)", err, delivery.MessageId)
+		log.Printf("Message processing failed: %v (MessageID: %s, RequestID: %s)", err, delivery.MessageId, requestID)
 
-		// Implement retry logic or dead letter queue handling here
-		if !opts.AutoAck {
-			// Reject and requeue the message for retry
-			delivery.Reject(true)
+		if opts.AutoAck {
+			return
 		}
+		if opts.Retry != nil {
+			c.retryOrDeadLetter(ctx, delivery, opts, err)
+			return
+		}
+		// Requeue for retry unless the handler says it will never succeed
+		delivery.Reject(!errors.Is(err, ErrPermanentFailure))
 		return
 	}
 
@@ -115,8 +356,17 @@ func (c *Consumer) handleMessage(ctx context.Context, delivery amqp.Delivery, ha
 		delivery.Ack(false)
 	}
 
-	log.Printf("Message processed successfully in %v (MessageID: %s)",
-		time.Since(start), delivery.MessageId)
+	log.Printf("Message processed successfully in %v (MessageID: %s, RequestID: %s)",
+		time.Since(start), delivery.MessageId, requestID)
+}
+
+// RequestID returns the ID of the request that led to the delivery being
+// published, or ""
+func RequestID(delivery amqp.Delivery) string {
+	if id, ok := delivery.Headers[requestid.AMQPHeader].(string); ok && id != "" {
+		return id
+	}
+	return delivery.CorrelationId
 }
 
 // CreateConsumerWithRetry creates a consumer with retry logic for resilience
@@ -151,3 +401,13 @@ func (c *Consumer) BindConsumer(queueName, routingKey, exchangeName string) erro
 		queueName, exchangeName, routingKey)
 	return nil
 }
+
+// MessageID returns the delivery's message ID, falling back to a hash of the
+// routing key and body for publishers that don't set one
+func MessageID(delivery amqp.Delivery) string {
+	if delivery.MessageId != "" {
+		return delivery.MessageId
+	}
+	sum := sha256.Sum256(append([]byte(delivery.RoutingKey+"\n"), delivery.Body...))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,37 @@
+package rabbitmq
+
+import (
+	"mime"
+	"slices"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Content types messages can be encoded with
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// MediaType returns a delivery's content type without parameters, treating a
+// missing content type as JSON
+func MediaType(delivery amqp.Delivery) string {
+	return normalizeContentType(delivery.ContentType)
+}
+
+func normalizeContentType(contentType string) string {
+	if contentType == "" {
+		return ContentTypeJSON
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return mediaType
+}
+
+// accepts reports whether a consumer accepting accept can handle delivery. An
+// empty accept list accepts everything.
+func accepts(accept []string, delivery amqp.Delivery) bool {
+	return len(accept) == 0 || slices.Contains(accept, MediaType(delivery))
+}
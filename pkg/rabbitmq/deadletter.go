@@ -0,0 +1,219 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var (
+	ErrOriginalDestinationUnknown = errors.New("dead-lettered message has no original routing key")
+	// ErrUnroutable is returned when a replayed message reached no queue
+	ErrUnroutable = errors.New("message was returned as unroutable")
+)
+
+// DeadLetter is a message parked in a dead-letter queue
+type DeadLetter struct {
+	ID                 string     `json:"id"`
+	FailureReason      string     `json:"failure_reason,omitempty"`
+	FailedAt           string     `json:"failed_at,omitempty"`
+	Attempts           int        `json:"attempts"`
+	OriginalExchange   string     `json:"original_exchange"`
+	OriginalRoutingKey string     `json:"original_routing_key"`
+	ContentType        string     `json:"content_type,omitempty"`
+	Headers            amqp.Table `json:"headers,omitempty"`
+	Body               string     `json:"body"`
+}
+
+// DeadLetterManager inspects and repairs the dead-letter queues declared by
+// DeclareRetryTopology. Every operation runs on its own channel so unacked
+// messages are returned to the queue when it finishes.
+type DeadLetterManager struct {
+	Client *Client
+}
+
+func NewDeadLetterManager(client *Client) *DeadLetterManager {
+	return &DeadLetterManager{Client: client}
+}
+
+// List returns up to limit messages from queue's dead-letter queue without
+// removing them
+func (m *DeadLetterManager) List(queue string, limit int) ([]DeadLetter, error) {
+	var letters []DeadLetter
+	err := m.scan(queue, func(ch *amqp.Channel, delivery amqp.Delivery) (bool, error) {
+		letters = append(letters, newDeadLetter(delivery))
+		return limit <= 0 || len(letters) < limit, nil
+	})
+	return letters, err
+}
+
+// Replay republishes the selected messages to the exchange and routing key they
+// were originally published to, with a fresh attempt count. A body in bodies
+// replaces the stored payload of the message with that ID. A message is only
+// removed from the dead-letter queue once the broker has confirmed it was
+// routed somewhere. It returns the number of messages replayed.
+func (m *DeadLetterManager) Replay(ctx context.Context, queue string, ids []string, bodies map[string][]byte) (int, error) {
+	pub, err := m.Client.Connection().Channel()
+	if err != nil {
+		return 0, err
+	}
+	defer pub.Close()
+	if err := pub.Confirm(false); err != nil {
+		return 0, err
+	}
+	// Returns arrive before the ack of the message they belong to
+	returns := pub.NotifyReturn(make(chan amqp.Return, 1))
+
+	selected := toSet(ids)
+	replayed := 0
+	err = m.scan(queue, func(ch *amqp.Channel, delivery amqp.Delivery) (bool, error) {
+		id := MessageID(delivery)
+		if !selected[id] {
+			return true, nil
+		}
+
+		letter := newDeadLetter(delivery)
+		if letter.OriginalRoutingKey == "" {
+			return false, ErrOriginalDestinationUnknown
+		}
+
+		body := delivery.Body
+		if edited, ok := bodies[id]; ok {
+			body = edited
+		}
+
+		headers := make(amqp.Table, len(delivery.Headers))
+		for k, v := range delivery.Headers {
+			headers[k] = v
+		}
+		for _, k := range []string{HeaderAttempts, HeaderFailureReason, HeaderFailedAt, HeaderOriginalExchange, HeaderOriginalRoutingKey} {
+			delete(headers, k)
+		}
+
+		confirmation, err := pub.PublishWithDeferredConfirmWithContext(ctx, letter.OriginalExchange, letter.OriginalRoutingKey, true, false, amqp.Publishing{
+			Headers:         headers,
+			ContentType:     delivery.ContentType,
+			ContentEncoding: delivery.ContentEncoding,
+			DeliveryMode:    amqp.Persistent,
+			CorrelationId:   delivery.CorrelationId,
+			MessageId:       id,
+			Timestamp:       time.Now(),
+			Type:            delivery.Type,
+			AppId:           delivery.AppId,
+			Body:            body,
+		})
+		if err != nil {
+			return false, err
+		}
+		acked, err := confirmation.WaitContext(ctx)
+		if err != nil {
+			return false, err
+		}
+		if !acked {
+			return false, ErrPublishNacked
+		}
+		select {
+		case ret := <-returns:
+			return false, fmt.Errorf("%w: %s", ErrUnroutable, ret.ReplyText)
+		default:
+		}
+
+		if err := delivery.Ack(false); err != nil {
+			return false, err
+		}
+
+		replayed++
+		delete(selected, id)
+		return len(selected) > 0, nil
+	})
+	return replayed, err
+}
+
+// Purge deletes the selected messages from queue's dead-letter queue, or every
+// message when ids is empty. It returns the number of messages removed.
+func (m *DeadLetterManager) Purge(queue string, ids []string) (int, error) {
+	if len(ids) == 0 {
+		ch, err := m.Client.Connection().Channel()
+		if err != nil {
+			return 0, err
+		}
+		defer ch.Close()
+		return ch.QueuePurge(DeadLetterQueue(queue), false)
+	}
+
+	selected := toSet(ids)
+	purged := 0
+	err := m.scan(queue, func(ch *amqp.Channel, delivery amqp.Delivery) (bool, error) {
+		id := MessageID(delivery)
+		if !selected[id] {
+			return true, nil
+		}
+		if err := delivery.Ack(false); err != nil {
+			return false, err
+		}
+		purged++
+		delete(selected, id)
+		return len(selected) > 0, nil
+	})
+	return purged, err
+}
+
+// scan walks the messages currently in queue's dead-letter queue, stopping when
+// visit returns false. Messages visit doesn't ack are requeued in their
+// original order when the channel closes.
+func (m *DeadLetterManager) scan(queue string, visit func(ch *amqp.Channel, delivery amqp.Delivery) (bool, error)) error {
+	ch, err := m.Client.Connection().Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	q, err := ch.QueueDeclarePassive(DeadLetterQueue(queue), true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	// Only look at what was there when we started, otherwise requeued
+	// messages would be visited again
+	for range q.Messages {
+		delivery, ok, err := ch.Get(q.Name, false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		more, err := visit(ch, delivery)
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+func newDeadLetter(delivery amqp.Delivery) DeadLetter {
+	letter := DeadLetter{
+		ID:          MessageID(delivery),
+		Attempts:    Attempts(delivery),
+		ContentType: delivery.ContentType,
+		Headers:     delivery.Headers,
+		Body:        string(delivery.Body),
+	}
+	letter.FailureReason, _ = delivery.Headers[HeaderFailureReason].(string)
+	letter.FailedAt, _ = delivery.Headers[HeaderFailedAt].(string)
+	letter.OriginalExchange, _ = delivery.Headers[HeaderOriginalExchange].(string)
+	letter.OriginalRoutingKey, _ = delivery.Headers[HeaderOriginalRoutingKey].(string)
+	return letter
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
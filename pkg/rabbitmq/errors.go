@@ -4,5 +4,17 @@ import "errors"
 
 var (
 	ErrConsumeFailed = errors.New("failed to consume from producer")
+	ErrClientClosed  = errors.New("rabbitmq client is closed")
+	ErrPublishNacked = errors.New("message was not confirmed by the broker")
+	// ErrPermanentFailure can be wrapped by a MessageHandler to reject a message
+	// without requeueing it, e.g. when its body can't be decoded
+	ErrPermanentFailure = errors.New("message cannot be processed")
+	ErrInvalidMessage   = errors.New("message does not match its schema")
+	// ErrUnsupportedContentType is returned for messages in an encoding the
+	// consumer or contract does not accept
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+	// ErrNotConnected and ErrConsumerStopped are reported by health checks
+	ErrNotConnected    = errors.New("rabbitmq client is not connected")
+	ErrConsumerStopped = errors.New("consumer is not running")
 )
 
@@ -0,0 +1,41 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+)
+
+// HealthCheck reports whether the client is connected to the broker
+func (c *Client) HealthCheck(context.Context) error {
+	if state := c.State(); state != StateConnected {
+		return fmt.Errorf("%w: %s", ErrNotConnected, state)
+	}
+	return nil
+}
+
+// HealthCheck reports whether every Consume call is still receiving
+// deliveries. A consumer waiting for the client to reconnect is not.
+func (c *Consumer) HealthCheck(context.Context) error {
+	c.mu.Lock()
+	subs := c.subscriptions
+	c.mu.Unlock()
+
+	if len(subs) == 0 {
+		return ErrConsumerStopped
+	}
+	for _, sub := range subs {
+		select {
+		case <-sub.done:
+			return fmt.Errorf("%w: %s", ErrConsumerStopped, sub.opts.ConsumerTag)
+		default:
+		}
+
+		sub.mu.Lock()
+		ch := sub.channel
+		sub.mu.Unlock()
+		if ch == nil || ch.IsClosed() {
+			return fmt.Errorf("%w: %s is waiting for a channel", ErrConsumerStopped, sub.opts.ConsumerTag)
+		}
+	}
+	return nil
+}
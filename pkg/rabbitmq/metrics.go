@@ -0,0 +1,37 @@
+package rabbitmq
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Values of the status label
+const (
+	statusSuccess = "success"
+	statusFailure = "failure"
+)
+
+var (
+	messagesPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rabbitmq_messages_published_total",
+		Help: "Messages published, by exchange, routing key and whether the broker confirmed them.",
+	}, []string{"exchange", "routing_key", "status"})
+
+	messagesConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rabbitmq_messages_consumed_total",
+		Help: "Messages delivered to handlers, by queue and whether they were handled.",
+	}, []string{"queue", "status"})
+
+	handlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "rabbitmq_handler_duration_seconds",
+		Help:    "Time taken to handle a delivery, by queue.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"queue"})
+)
+
+func statusLabel(err error) string {
+	if err != nil {
+		return statusFailure
+	}
+	return statusSuccess
+}
@@ -0,0 +1,120 @@
+package rabbitmq
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const defaultPublisherChannels = 4
+
+// channelPool hands out publisher channels in confirm mode. Channels are opened
+// lazily and replaced when they turn out to be closed, e.g. after a reconnect.
+type channelPool struct {
+	client *Client
+	// tokens bounds how many channels are in use at once
+	tokens chan struct{}
+	idle   chan *amqp.Channel
+}
+
+func newChannelPool(client *Client, size int) *channelPool {
+	if size <= 0 {
+		size = defaultPublisherChannels
+	}
+	tokens := make(chan struct{}, size)
+	for range size {
+		tokens <- struct{}{}
+	}
+	return &channelPool{
+		client: client,
+		tokens: tokens,
+		idle:   make(chan *amqp.Channel, size),
+	}
+}
+
+func (p *channelPool) get(ctx context.Context) (*amqp.Channel, error) {
+	select {
+	case <-p.tokens:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	for {
+		select {
+		case ch := <-p.idle:
+			if ch.IsClosed() {
+				continue
+			}
+			return ch, nil
+		default:
+		}
+
+		ch, err := p.open()
+		if err != nil {
+			p.tokens <- struct{}{}
+			return nil, err
+		}
+		return ch, nil
+	}
+}
+
+func (p *channelPool) put(ch *amqp.Channel) {
+	if !ch.IsClosed() {
+		p.idle <- ch
+	}
+	p.tokens <- struct{}{}
+}
+
+func (p *channelPool) open() (*amqp.Channel, error) {
+	conn := p.client.Connection()
+	if conn == nil || conn.IsClosed() {
+		return nil, amqp.ErrClosed
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return nil, err
+	}
+	return ch, nil
+}
+
+func (p *channelPool) close() {
+	for {
+		select {
+		case ch := <-p.idle:
+			ch.Close()
+		default:
+			return
+		}
+	}
+}
+
+// publish sends msg on a pooled confirm channel and waits for the broker's ack.
+// The channel is returned to the pool as soon as the message is sent, so other
+// goroutines can publish while this one waits; confirms are matched to this
+// message by its delivery tag.
+func (c *Client) publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error {
+	ch, err := c.publishers.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, false, false, msg)
+	c.publishers.put(ch)
+	if err != nil {
+		return err
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return ErrPublishNacked
+	}
+	return nil
+}
@@ -3,40 +3,79 @@ package rabbitmq
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/rabbitmq/amqp091-go"
+	"github.com/whotterre/entritts/pkg/requestid"
 )
 
 func NewPublisher(client *Client) *Publisher {
 	return &Publisher{Client: client}
 }
 
+// Publish publishes an event and waits for the broker to confirm it. It is safe
+// to call from many goroutines at once.
 func (p *Publisher) Publish(event Event) error {
-	ctx := context.Background()
+	return p.PublishContext(context.Background(), event)
+}
+
+// PreferredContentType is the encoding set on the publisher, or JSON
+func (p *Publisher) PreferredContentType() string {
+	if p.ContentType == "" {
+		return ContentTypeJSON
+	}
+	return p.ContentType
+}
+
+// PublishContext is Publish with a context bounding the wait for the confirm
+func (p *Publisher) PublishContext(ctx context.Context, event Event) error {
+	if p.Validate != nil {
+		if err := p.Validate(event); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+	}
+
 	err := p.Client.EnsureExchange(event.Exchange, "topic")
 	if err != nil {
 		return err
 	}
 
-	message := amqp091.Publishing{
-		ContentType:  "application/json",
-		Body:         event.Body,
-		Timestamp:    time.Now(),
-		Headers:      event.Headers,
-		DeliveryMode: amqp091.Persistent,
+	contentType := event.ContentType
+	if contentType == "" {
+		contentType = ContentTypeJSON
+	}
+
+	// Carry the originating request's ID so consumers can log it
+	correlationID := event.CorrelationID
+	if correlationID == "" {
+		correlationID = requestid.FromContext(ctx)
 	}
+	headers := make(amqp091.Table, len(event.Headers)+3)
+	for k, v := range event.Headers {
+		headers[k] = v
+	}
+	if correlationID != "" {
+		headers[requestid.AMQPHeader] = correlationID
+	}
+
+	ctx, span := startPublishSpan(ctx, event, headers)
+	defer func() { endSpan(span, err) }()
 
-	err = p.Client.Channel.PublishWithContext(
-		ctx,
-		event.Exchange,
-		event.RoutingKey,
-		false,
-		false,
-		message,
-	)
+	message := amqp091.Publishing{
+		ContentType:   contentType,
+		MessageId:     event.MessageID,
+		Type:          event.Type,
+		CorrelationId: correlationID,
+		Body:          event.Body,
+		Timestamp:     time.Now(),
+		Headers:       headers,
+		DeliveryMode:  amqp091.Persistent,
+	}
 
+	err = p.Client.publish(ctx, event.Exchange, event.RoutingKey, message)
+	messagesPublished.WithLabelValues(event.Exchange, event.RoutingKey, statusLabel(err)).Inc()
 	if err != nil {
 		return err
 	}
@@ -68,7 +107,7 @@ func (p *Publisher) PublishWithRetry(event Event, maxRetries int, delay time.Dur
 			return nil
 		}
 		log.Printf("⚠️ Failed to publish (attempt %d/%d): %v", i+1, maxRetries, err)
-		if i < maxRetries - 1 {
+		if i < maxRetries-1 {
 			time.Sleep(delay)
 		}
 
@@ -76,24 +115,8 @@ func (p *Publisher) PublishWithRetry(event Event, maxRetries int, delay time.Dur
 	return err
 }
 
+// PublishWithConfirmation is kept for existing callers; Publish always waits
+// for the broker's confirmation now
 func (p *Publisher) PublishWithConfirmation(event Event) error {
-	err := p.Client.Channel.Confirm(false)
-	if err != nil {
-		return err
-	}
-
-	confirms := p.Client.Channel.NotifyPublish(make(chan amqp091.Confirmation, 1))
-	err = p.Publish(event)
-	if err != nil {
-		return err
-	}
-
-	if confirmed := <-confirms; confirmed.Ack {
-		log.Printf("Message confirmed for %s:%s", event.Exchange, event.RoutingKey)
-		return nil
-	} else {
-		log.Printf("Message not confirmed for %s:%s", event.Exchange, event.RoutingKey)
-		return err
-	}
-
+	return p.Publish(event)
 }
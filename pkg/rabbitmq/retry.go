@@ -0,0 +1,186 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Headers used to track retries and record why a message was dead-lettered
+const (
+	HeaderAttempts           = "x-attempts"
+	HeaderFailureReason      = "x-failure-reason"
+	HeaderFailedAt           = "x-failed-at"
+	HeaderOriginalExchange   = "x-original-exchange"
+	HeaderOriginalRoutingKey = "x-original-routing-key"
+)
+
+const maxFailureReasonLength = 1024
+
+// RetryPolicy bounds how often a failing message is redelivered and how long to
+// wait between attempts
+type RetryPolicy struct {
+	// MaxAttempts counts every delivery, including the first one
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+}
+
+// DefaultRetryPolicy retries up to 4 times, waiting 1s, 2s, 4s and 8s
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:  5,
+		InitialDelay: time.Second,
+		MaxDelay:     5 * time.Minute,
+		Multiplier:   2,
+	}
+}
+
+// Delay returns how long to wait before the given retry (1 for the first retry)
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := time.Duration(float64(p.InitialDelay) * math.Pow(multiplier, float64(retry-1)))
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// DeadLetterExchange is the exchange exhausted messages from queue are parked through
+func DeadLetterExchange(queue string) string {
+	return queue + ".dlx"
+}
+
+// DeadLetterQueue holds messages from queue that exhausted their retries
+func DeadLetterQueue(queue string) string {
+	return queue + ".dlq"
+}
+
+// RetryQueue is the delay queue holding messages from queue for delay. The
+// delay is part of the name because a queue's TTL can't change once declared:
+// a new policy declares new queues, and the old ones empty back into queue.
+func RetryQueue(queue string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%dms", queue, delay.Milliseconds())
+}
+
+// DeclareRetryTopology declares the dead-letter exchange and queue for queue and
+// one delay queue per retry delay. Delay queues have no consumers; once a message's
+// TTL runs out the broker dead-letters it back onto queue.
+func (c *Client) DeclareRetryTopology(queue string, policy RetryPolicy) error {
+	dlx := DeadLetterExchange(queue)
+	err := c.declare("exchange:"+dlx, func(ch *amqp.Channel) error {
+		return ch.ExchangeDeclare(dlx, "direct", true, false, false, false, nil)
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := c.EnsureQueue(DeadLetterQueue(queue)); err != nil {
+		return err
+	}
+	if err := c.BindQueue(DeadLetterQueue(queue), queue, dlx); err != nil {
+		return err
+	}
+
+	for retry := 1; retry < policy.MaxAttempts; retry++ {
+		delay := policy.Delay(retry)
+		name := RetryQueue(queue, delay)
+		err := c.declare("queue:"+name, func(ch *amqp.Channel) error {
+			_, err := ch.QueueDeclare(name, true, false, false, false, amqp.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queue,
+			})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Attempts returns how many times the delivery has been handed to a consumer,
+// counting this delivery
+func Attempts(delivery amqp.Delivery) int {
+	switch v := delivery.Headers[HeaderAttempts].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 1
+}
+
+// retryOrDeadLetter schedules a failed delivery for a delayed retry, or parks it
+// in the dead-letter queue once it has no attempts left. The original delivery
+// is only acked after the broker has confirmed the copy.
+func (c *Consumer) retryOrDeadLetter(ctx context.Context, delivery amqp.Delivery, opts ConsumeOptions, handlerErr error) {
+	attempts := Attempts(delivery)
+	headers := copyHeaders(delivery)
+
+	exchange, routingKey := "", RetryQueue(opts.QueueName, opts.Retry.Delay(attempts))
+	if errors.Is(handlerErr, ErrPermanentFailure) || attempts >= opts.Retry.MaxAttempts {
+		reason := handlerErr.Error()
+		if len(reason) > maxFailureReasonLength {
+			reason = reason[:maxFailureReasonLength]
+		}
+		headers[HeaderFailureReason] = reason
+		headers[HeaderFailedAt] = time.Now().UTC().Format(time.RFC3339)
+		exchange, routingKey = DeadLetterExchange(opts.QueueName), opts.QueueName
+	} else {
+		headers[HeaderAttempts] = int32(attempts + 1)
+	}
+
+	err := c.Client.publish(ctx, exchange, routingKey, amqp.Publishing{
+		Headers:         headers,
+		ContentType:     delivery.ContentType,
+		ContentEncoding: delivery.ContentEncoding,
+		DeliveryMode:    amqp.Persistent,
+		CorrelationId:   delivery.CorrelationId,
+		MessageId:       delivery.MessageId,
+		Timestamp:       delivery.Timestamp,
+		Type:            delivery.Type,
+		AppId:           delivery.AppId,
+		Body:            delivery.Body,
+	})
+	if err != nil {
+		// Fall back to a plain requeue rather than losing the message
+		log.Printf("Failed to schedule retry for message %s: %v", delivery.MessageId, err)
+		delivery.Reject(true)
+		return
+	}
+
+	if exchange != "" {
+		log.Printf("Message %s dead-lettered to %s after %d attempt(s): %v",
+			delivery.MessageId, DeadLetterQueue(opts.QueueName), attempts, handlerErr)
+	} else {
+		log.Printf("Message %s scheduled for retry %d/%d",
+			delivery.MessageId, attempts, opts.Retry.MaxAttempts-1)
+	}
+	delivery.Ack(false)
+}
+
+// copyHeaders copies the delivery's headers and records where it was originally
+// published, which is lost once it travels through the delay queues
+func copyHeaders(delivery amqp.Delivery) amqp.Table {
+	headers := make(amqp.Table, len(delivery.Headers)+4)
+	for k, v := range delivery.Headers {
+		headers[k] = v
+	}
+	if _, ok := headers[HeaderOriginalRoutingKey]; !ok {
+		headers[HeaderOriginalExchange] = delivery.Exchange
+		headers[HeaderOriginalRoutingKey] = delivery.RoutingKey
+	}
+	return headers
+}
@@ -0,0 +1,78 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/whotterre/entritts/pkg/rabbitmq")
+
+// headerCarrier lets the global propagator read and write W3C trace context
+// in AMQP headers
+type headerCarrier amqp.Table
+
+func (h headerCarrier) Get(key string) string {
+	value, _ := h[key].(string)
+	return value
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h[key] = value
+}
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// startPublishSpan starts a producer span and writes its context into headers
+func startPublishSpan(ctx context.Context, event Event, headers amqp.Table) (context.Context, trace.Span) {
+	ctx, span := tracer.Start(ctx, fmt.Sprintf("%s publish", event.Exchange),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "rabbitmq"),
+			attribute.String("messaging.operation", "publish"),
+			attribute.String("messaging.destination.name", event.Exchange),
+			attribute.String("messaging.rabbitmq.destination.routing_key", event.RoutingKey),
+			attribute.String("messaging.message.id", event.MessageID),
+		),
+	)
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier(headers))
+	return ctx, span
+}
+
+// startConsumeSpan starts a consumer span continuing the trace the publisher
+// left in the delivery's headers
+func startConsumeSpan(ctx context.Context, delivery amqp.Delivery, queue string) (context.Context, trace.Span) {
+	if delivery.Headers != nil {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier(delivery.Headers))
+	}
+	return tracer.Start(ctx, fmt.Sprintf("%s process", queue),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "rabbitmq"),
+			attribute.String("messaging.operation", "process"),
+			attribute.String("messaging.destination.name", delivery.Exchange),
+			attribute.String("messaging.rabbitmq.destination.routing_key", delivery.RoutingKey),
+			attribute.String("messaging.message.id", delivery.MessageId),
+		),
+	)
+}
+
+// endSpan records err, if any, and ends span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -1,29 +1,89 @@
 package rabbitmq
 
-import amqp "github.com/rabbitmq/amqp091-go"
+import (
+	"log"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
 
 // Client holds RabbitMQ connection and channel
 type Client struct {
-	Conn    *amqp.Connection
-	Channel *amqp.Channel
+	cfg    Config
+	logger *log.Logger
+
+	mu      sync.RWMutex
+	conn    *amqp.Connection
+	channel *amqp.Channel
+	state   ConnectionState
+	// changed is closed and replaced on every state change
+	changed   chan struct{}
+	done      chan struct{}
+	listeners []func(ConnectionState, error)
+
+	// Confirm mode channels used for publishing, separate from the consumer channel
+	publishers *channelPool
+
+	// Exchanges, queues and bindings to re-declare after a reconnect
+	declarations     map[string]func(*amqp.Channel) error
+	declarationOrder []string
+}
+
+// ConnectionState describes the client's connection to the broker
+type ConnectionState int
+
+const (
+	StateConnected ConnectionState = iota
+	StateReconnecting
+	StateClosed
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateClosed:
+		return "closed"
+	}
+	return "unknown"
 }
 
 // Publisher wraps the client with publishing capabilities
 type Publisher struct {
 	Client *Client
+	// Validate, when set, is called before each publish; events it rejects are
+	// not sent and PublishContext returns ErrInvalidMessage
+	Validate func(event Event) error
+	// ContentType is the encoding callers that support several should use,
+	// defaulting to JSON
+	ContentType string
 }
 
 // Event represents a message to be published
 type Event struct {
 	Exchange   string
 	RoutingKey string
-	Body       []byte
-	Headers    amqp.Table
+	// MessageID should be stable across retries so consumers can deduplicate
+	MessageID     string
+	Type          string
+	CorrelationID string
+	// ContentType of Body, defaulting to JSON
+	ContentType string
+	Body        []byte
+	Headers     amqp.Table
 }
 
 // Config holds RabbitMQ connection configuration
 type Config struct {
 	URL string
+	// Backoff between reconnect attempts, defaulting to 1s doubling up to 30s
+	ReconnectInitialDelay time.Duration
+	ReconnectMaxDelay     time.Duration
+	// PublisherChannels caps the confirm mode channels used for publishing, default 4
+	PublisherChannels int
 }
 
 // ConsumerConfig holds configuration for message consumers
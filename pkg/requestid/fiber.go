@@ -0,0 +1,36 @@
+package requestid
+
+import "github.com/gofiber/fiber/v2"
+
+// MaxLength bounds the IDs accepted from callers
+const MaxLength = 128
+
+// Valid reports whether id is safe to accept from a caller: non-empty, at most
+// MaxLength long and made only of letters, digits, '-', '_' and '.'
+func Valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware picks up the X-Request-ID assigned by the gateway, or assigns one
+// when it is missing or malformed, and carries it in the request's locals
+// ("requestID") and user context for the services and outgoing messages
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(Header)
+		if !Valid(id) {
+			id = New()
+		}
+		c.Set(Header, id)
+		c.Locals("requestID", id)
+		c.SetUserContext(WithID(c.UserContext(), id))
+		return c.Next()
+	}
+}
@@ -0,0 +1,40 @@
+// Package requestid carries the ID the gateway assigns to each request through
+// HTTP calls, contexts and broker messages so their logs can be tied together.
+package requestid
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Header carries the request ID on HTTP requests and responses
+const Header = "X-Request-ID"
+
+// AMQPHeader carries the request ID on broker messages, next to the
+// CorrelationId property
+const AMQPHeader = "x-request-id"
+
+type contextKey struct{}
+
+// New returns a fresh request ID
+func New() string {
+	return uuid.NewString()
+}
+
+// WithID returns a copy of ctx carrying the request ID
+func WithID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, or ""
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "tracing:span"
+
+var gormTracer = otel.Tracer("github.com/whotterre/entritts/pkg/tracing/gorm")
+
+// InstrumentGORM records a span for every statement db runs. Statements are
+// children of the span in the context passed with db.WithContext.
+func InstrumentGORM(db *gorm.DB) error {
+	type registerFunc func(name string, fn func(*gorm.DB)) error
+	callbacks := db.Callback()
+	operations := []struct {
+		name          string
+		before, after registerFunc
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register, callbacks.Create().After("gorm:create").Register},
+		{"query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register, callbacks.Update().After("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register, callbacks.Delete().After("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register},
+	}
+
+	for _, op := range operations {
+		spanName := "gorm." + op.name
+		err := op.before("tracing:before_"+op.name, func(tx *gorm.DB) {
+			ctx, span := gormTracer.Start(tx.Statement.Context, spanName,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(attribute.String("db.system", tx.Dialector.Name())),
+			)
+			tx.Statement.Context = ctx
+			tx.InstanceSet(gormSpanKey, span)
+		})
+		if err != nil {
+			return err
+		}
+		if err := op.after("tracing:after_"+op.name, endGORMSpan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func endGORMSpan(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("db.statement", tx.Statement.SQL.String()),
+		attribute.String("db.sql.table", tx.Statement.Table),
+		attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+	)
+	if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(tx.Error)
+		span.SetStatus(codes.Error, tx.Error.Error())
+	}
+}
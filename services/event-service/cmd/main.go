@@ -4,7 +4,6 @@ import (
 	"context"
 	"event-service/internal/config"
 	"event-service/internal/models"
-	"event-service/internal/rabbitmq"
 	"event-service/internal/repository"
 	"event-service/internal/routes"
 	"event-service/internal/services"
@@ -15,8 +14,16 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/whotterre/entritts/pkg/contracts"
 	"github.com/whotterre/entritts/pkg/database"
-
+	"github.com/whotterre/entritts/pkg/health"
+	"github.com/whotterre/entritts/pkg/metrics"
+	"github.com/whotterre/entritts/pkg/outbox"
+	rabbit "github.com/whotterre/entritts/pkg/rabbitmq"
+	"github.com/whotterre/entritts/pkg/requestid"
+	"github.com/whotterre/entritts/pkg/tracing"
+
+	"github.com/gofiber/contrib/otelfiber/v2"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"go.uber.org/zap"
@@ -30,7 +37,10 @@ func main() {
 	app := fiber.New()
 
 	// Middleware
-	app.Use(logger.New())
+	app.Use(requestid.Middleware())
+	app.Use(logger.New(logger.Config{
+		Format: "[${time}] ${status} - ${latency} ${method} ${path} request_id=${respHeader:X-Request-ID}\n",
+	}))
 
 	// Logger
 	logger, err := zap.NewProduction()
@@ -40,6 +50,16 @@ func main() {
 
 	cfg = config.LoadConfig()
 
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.Error("Failed to set up tracing", zap.Error(err))
+		return
+	}
+	defer shutdownTracing(context.Background())
+	app.Use(otelfiber.Middleware())
+	app.Use(metrics.Middleware())
+	app.Get("/metrics", metrics.Handler())
+
 	// Populate db config
 	dbPort, _ := strconv.Atoi(cfg.DBPort)
 	dbConfig := database.DBConfig{
@@ -57,6 +77,14 @@ func main() {
 		logger.Error("Failed to initialize PostgreSQL connection for event_db", zap.Error(err))
 		return
 	}
+	if err := tracing.InstrumentGORM(db); err != nil {
+		logger.Error("Failed to instrument database", zap.Error(err))
+		return
+	}
+	if err := metrics.RegisterDB(db, cfg.DBName); err != nil {
+		logger.Error("Failed to register database metrics", zap.Error(err))
+		return
+	}
 
 	if err := db.AutoMigrate(&models.EventCategory{}); err != nil {
 		logger.Error("Failed to migrate EventCategory", zap.Error(err))
@@ -79,30 +107,111 @@ func main() {
 	}
 
 	// Migrate outbox table
-	if err := db.AutoMigrate(&models.OutboxEvent{}); err != nil {
-		logger.Error("Failed to migrate OutboxEvent", zap.Error(err))
+	if err := db.AutoMigrate(&outbox.Message{}); err != nil {
+		logger.Error("Failed to migrate outbox", zap.Error(err))
+		return
+	}
+	if err := outbox.InstallNotifyTrigger(db); err != nil {
+		logger.Error("Failed to install outbox notify trigger", zap.Error(err))
 		return
 	}
 
 	logger.Info("Database migration completed successfully")
 
-	// Initialize RabbitMQ producer in the service
-	producer, err := rabbitmq.NewEventProducer(cfg.RabbitMQURL, logger)
+	// Initialize RabbitMQ publisher for the outbox relay
+	rabbitLogger := log.New(os.Stdout, "rabbitmq: ", log.LstdFlags)
+	rabbitClient, err := rabbit.NewClient(rabbit.Config{URL: cfg.RabbitMQURL}, rabbitLogger)
 	if err != nil {
 		logger.Error("Failed to initialize RabbitMQ broker", zap.Error(err))
 		return
 	}
+	defer rabbitClient.Close(rabbitLogger)
+
+	publisher := rabbit.NewPublisher(rabbitClient)
+	if cfg.ValidateMessages {
+		publisher.Validate = contracts.ValidateEvent
+	}
+
+	outboxStore := outbox.NewStore(db)
+	if cfg.MessageEncoding == "protobuf" {
+		outboxStore.ContentType = rabbit.ContentTypeProtobuf
+	}
+
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	relayLogger := log.New(os.Stdout, "outbox: ", log.LstdFlags)
+
+	// In CDC mode the slot has to exist before anything else is written to the
+	// outbox, or those rows never reach its stream
+	var cdcRelay *outbox.CDCRelay
+	if cfg.OutboxMode == "cdc" {
+		cdcRelay = outbox.NewCDCRelay(outboxStore, publisher, outbox.CDCConfig{
+			DSN:             dbConfig.DSN(),
+			SlotName:        cfg.OutboxCDCSlot,
+			MaxAttempts:     cfg.Outbox.MaxAttempts,
+			Retention:       cfg.Outbox.Retention,
+			CleanupInterval: cfg.Outbox.CleanupInterval,
+		}, relayLogger)
+		if err := outbox.RegisterCDCMetrics(cdcRelay); err != nil {
+			logger.Error("Failed to register outbox metrics", zap.Error(err))
+			stopRelay()
+			return
+		}
+		if err := cdcRelay.Setup(relayCtx); err != nil {
+			logger.Error("Failed to set up outbox replication", zap.Error(err))
+			stopRelay()
+			return
+		}
+	}
+
+	// Rows written before the shared outbox are published through it
+	if err := services.DrainLegacyOutbox(db, repository.NewOutboxRepository(db), outboxStore, logger); err != nil {
+		logger.Error("Failed to move legacy outbox events", zap.Error(err))
+		stopRelay()
+		return
+	}
 
-	// Initialize outbox repository and service
-	outboxRepo := repository.NewOutboxRepository(db)
-	outboxService := services.NewOutboxService(outboxRepo, producer, logger)
+	// Start outbox relay in background
+	if cdcRelay != nil {
+		// Rows the stream doesn't carry, e.g. written before the slot, go out
+		// first; the backfill retries until they're all published
+		go func() {
+			if err := cdcRelay.Backfill(relayCtx); err != nil {
+				return
+			}
+			cdcRelay.Run(relayCtx)
+		}()
+	} else {
+		if err := outbox.RegisterMetrics(outboxStore); err != nil {
+			logger.Error("Failed to register outbox metrics", zap.Error(err))
+			stopRelay()
+			return
+		}
+		if cfg.OutboxListen {
+			cfg.Outbox.ListenDSN = dbConfig.DSN()
+		}
+		relay := outbox.NewRelay(outboxStore, publisher, cfg.Outbox, relayLogger)
+		go relay.Run(relayCtx)
+	}
 
-	// Start outbox processor in background
-	stopCh := make(chan struct{})
-	go outboxService.StartOutboxProcessor(5*time.Second, stopCh)
+	// Readiness needs the database and the broker, and fails once the outbox
+	// falls too far behind
+	checker := health.NewChecker(cfg.AppName, cfg.HealthCheckTimeout)
+	checker.Add("database", func(ctx context.Context) error {
+		return database.HealthCheckContext(ctx, db)
+	})
+	checker.Add("rabbitmq", rabbitClient.HealthCheck)
+	if cdcRelay != nil {
+		checker.Add("outbox", cdcRelay.LagCheck(cfg.OutboxMaxLag))
+	} else {
+		checker.Add("outbox", outboxStore.LagCheck(cfg.OutboxMaxLag))
+	}
+	app.Get("/api/v1/health/live", checker.Live)
+	app.Get("/api/v1/health/ready", checker.Ready)
+	// The gateway probes this one; HTTP requests only need the database
+	app.Get("/api/v1/health", checker.ReadyFor("database"))
 
 	// Setup routes
-	routes.SetupRoutes(app, db, logger)
+	routes.SetupRoutes(app, db, outboxStore, logger)
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
@@ -118,7 +227,7 @@ func main() {
 	<-c
 	logger.Info("Shutting down server...")
 
-	close(stopCh)
+	stopRelay()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"event-service/internal/dto"
+	"event-service/internal/middleware"
 	"event-service/internal/services"
 	"net/http"
 
@@ -23,10 +24,11 @@ func NewEventHandler(eventService services.EventService, logger *zap.Logger) *Ev
 
 func (h *EventHandler) CreateNewEvent(c *fiber.Ctx) error {
 	var req dto.CreateNewEventDto
+	logger := middleware.RequestLogger(c, h.logger)
 
 	// Parse request body
 	if err := c.BodyParser(&req); err != nil {
-		h.logger.Error("Failed to parse request body",
+		logger.Error("Failed to parse request body",
 			zap.Error(err),
 			zap.String("content_type", c.Get("Content-Type")),
 		)
@@ -41,15 +43,15 @@ func (h *EventHandler) CreateNewEvent(c *fiber.Ctx) error {
 		})
 	}
 
-	h.logger.Info("Processing event creation request",
+	logger.Info("Processing event creation request",
 		zap.String("title", req.Title),
 		zap.String("organizer_id", req.OrganizerId),
 	)
 
 	// Create event via service
-	newEvent, err := h.eventService.CreateNewEvent(req)
+	newEvent, err := h.eventService.CreateNewEvent(c.UserContext(), req)
 	if err != nil {
-		h.logger.Error("Failed to create event",
+		logger.Error("Failed to create event",
 			zap.Error(err),
 			zap.String("title", req.Title),
 			zap.String("organizer_id", req.OrganizerId),
@@ -91,7 +93,7 @@ func (h *EventHandler) CreateNewEvent(c *fiber.Ctx) error {
 		})
 	}
 
-	h.logger.Info("Event created successfully",
+	logger.Info("Event created successfully",
 		zap.String("event_id", newEvent.EventId.String()),
 		zap.String("title", newEvent.Title),
 	)
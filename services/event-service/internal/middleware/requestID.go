@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// RequestLogger returns logger tagged with the ID requestid.Middleware stored
+// for the request
+func RequestLogger(c *fiber.Ctx, logger *zap.Logger) *zap.Logger {
+	if id, ok := c.Locals("requestID").(string); ok {
+		return logger.With(zap.String("request_id", id))
+	}
+	return logger
+}
@@ -6,6 +6,9 @@ import (
 	"github.com/google/uuid"
 )
 
+// OutboxEvent is a row of the legacy outbox_events table. Nothing writes to it
+// any more: DrainLegacyOutbox moves unpublished rows into pkg/outbox at startup
+// and marks them published. Columns added to the table later are left alone.
 type OutboxEvent struct {
 	ID          uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
 	AggregateID string     `gorm:"type:varchar(255);not null" json:"aggregate_id"`
@@ -2,16 +2,18 @@ package repository
 
 import (
 	"event-service/internal/models"
-	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// OutboxRepository reads the legacy outbox_events table, which predates the
+// shared outbox in pkg/outbox
 type OutboxRepository interface {
-	CreateOutboxEvent(tx *gorm.DB, aggregateID, eventType, eventData string) error
-	GetUnpublishedEvents() ([]models.OutboxEvent, error)
-	MarkAsPublished(eventID uuid.UUID) error
+	HasLegacyTable() bool
+	GetUnpublishedEvents(tx *gorm.DB) ([]models.OutboxEvent, error)
+	MarkAsPublished(tx *gorm.DB, eventIDs []uuid.UUID) error
 }
 
 type outboxRepository struct {
@@ -22,28 +24,24 @@ func NewOutboxRepository(db *gorm.DB) OutboxRepository {
 	return &outboxRepository{db: db}
 }
 
-func (r *outboxRepository) CreateOutboxEvent(tx *gorm.DB, aggregateID, eventType, eventData string) error {
-	outboxEvent := &models.OutboxEvent{
-		AggregateID: aggregateID,
-		EventType:   eventType,
-		EventData:   eventData,
-		Published:   false,
-	}
-	return tx.Create(outboxEvent).Error
+func (r *outboxRepository) HasLegacyTable() bool {
+	return r.db.Migrator().HasTable(&models.OutboxEvent{})
 }
 
-func (r *outboxRepository) GetUnpublishedEvents() ([]models.OutboxEvent, error) {
+func (r *outboxRepository) GetUnpublishedEvents(tx *gorm.DB) ([]models.OutboxEvent, error) {
 	var events []models.OutboxEvent
-	err := r.db.Where("published = ?", false).Order("created_at ASC").Find(&events).Error
+	// Only columns every version of the table has. The rows stay locked until tx
+	// ends, so replicas draining at the same time don't move them twice.
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("published = ?", false).Order("created_at ASC").Find(&events).Error
 	return events, err
 }
 
-func (r *outboxRepository) MarkAsPublished(eventID uuid.UUID) error {
-	now := time.Now()
-	return r.db.Model(&models.OutboxEvent{}).
-		Where("id = ?", eventID).
+func (r *outboxRepository) MarkAsPublished(tx *gorm.DB, eventIDs []uuid.UUID) error {
+	return tx.Model(&models.OutboxEvent{}).
+		Where("id IN ?", eventIDs).
 		Updates(map[string]interface{}{
 			"published":    true,
-			"published_at": &now,
+			"published_at": gorm.Expr("NOW()"),
 		}).Error
 }
@@ -6,20 +6,20 @@ import (
 	"event-service/internal/services"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/whotterre/entritts/pkg/outbox"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(app *fiber.App, db *gorm.DB, logger *zap.Logger) {
+func SetupRoutes(app *fiber.App, db *gorm.DB, outboxStore *outbox.Store, logger *zap.Logger) {
 	api := app.Group("/api/v1")
 
 	// Event repositories and services
 	eventRepo := repository.NewEventRepository(db)
-	outBoxRepo := repository.NewOutboxRepository(db)
 	eventCategoryRepo := repository.NewEventCategoryRepository(db)
 	eventVenueRepo := repository.NewEventVenueRepository(db)
 
-	eventService := services.NewEventService(eventRepo, eventCategoryRepo, eventVenueRepo, outBoxRepo, db, logger)
+	eventService := services.NewEventService(eventRepo, eventCategoryRepo, eventVenueRepo, outboxStore, db, logger)
 	eventHandler := handlers.NewEventHandler(eventService, logger)
 
 	// Category repositories and services
@@ -33,13 +33,6 @@ func SetupRoutes(app *fiber.App, db *gorm.DB, logger *zap.Logger) {
 	venueHandler := handlers.NewEventVenueHandler(venueService, logger)
 
 	events := api.Group("/events")
-	// Basic health check endpoint
-	api.Get("/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"status":  "OK",
-			"service": "event-service",
-		})
-	})
 	// Event routes
 	events.Post("/", eventHandler.CreateNewEvent)
 	// Event category routes
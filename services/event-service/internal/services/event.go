@@ -1,7 +1,7 @@
 package services
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"event-service/internal/dto"
 	"event-service/internal/models"
@@ -10,19 +10,25 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/whotterre/entritts/pkg/contracts"
+	"github.com/whotterre/entritts/pkg/outbox"
+	"github.com/whotterre/entritts/pkg/requestid"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
+// producerName identifies this service in message envelopes
+const producerName = "event-service"
+
 type EventService interface {
-	CreateNewEvent(eventData dto.CreateNewEventDto) (*dto.CreateNewEventResponse, error)
+	CreateNewEvent(ctx context.Context, eventData dto.CreateNewEventDto) (*dto.CreateNewEventResponse, error)
 }
 
 type eventService struct {
 	eventRepository repository.EventRepository
 	eventCategoryRepository repository.EventCategoryRepository
 	eventVenueRepository repository.EventVenueRepository
-	outboxRepo      repository.OutboxRepository
+	outboxStore     *outbox.Store
 	db              *gorm.DB
 	logger          *zap.Logger
 }
@@ -30,21 +36,25 @@ type eventService struct {
 func NewEventService(eventRepository repository.EventRepository, 
 	eventCategoryRepository repository.EventCategoryRepository,
 	eventVenueRepository repository.EventVenueRepository, 
-	outboxRepo repository.OutboxRepository, 
+	outboxStore *outbox.Store,
 	db *gorm.DB, logger *zap.Logger) EventService {
 	return &eventService{
 		eventRepository: eventRepository,
 		eventCategoryRepository: eventCategoryRepository,
 		eventVenueRepository: eventVenueRepository,
-		outboxRepo:      outboxRepo,
+		outboxStore:     outboxStore,
 		db:              db,
 		logger:          logger,
 	}
 }
 
-func (s *eventService) CreateNewEvent(eventData dto.CreateNewEventDto) (*dto.CreateNewEventResponse, error) {
+// CreateNewEvent stores the event and its event.created message together. The
+// message is correlated with the request ID carried by ctx.
+func (s *eventService) CreateNewEvent(ctx context.Context, eventData dto.CreateNewEventDto) (*dto.CreateNewEventResponse, error) {
+	logger := s.logger.With(zap.String("request_id", requestid.FromContext(ctx)))
+
 	// Start database transaction
-	tx := s.db.Begin()
+	tx := s.db.WithContext(ctx).Begin()
 	if tx.Error != nil {
 		return nil, tx.Error
 	}
@@ -111,21 +121,28 @@ func (s *eventService) CreateNewEvent(eventData dto.CreateNewEventDto) (*dto.Cre
 	}
 
 	// Create outbox event in the same transaction
-	eventDataJson, err := json.Marshal(map[string]interface{}{
-		"event_id":     newEvent.EventId.String(),
-		"organizer_id": newEvent.OrganizerId.String(),
-		"event_title":  newEvent.Title,
-		"ticket_types": eventData.TicketTypes,
+	ticketTypes := make([]contracts.TicketType, 0, len(eventData.TicketTypes))
+	for _, ticketType := range eventData.TicketTypes {
+		ticketTypes = append(ticketTypes, contracts.TicketType{
+			ID:            ticketType.ID.String(),
+			Name:          ticketType.Name,
+			Description:   ticketType.Description,
+			Price:         ticketType.Price.String(),
+			TotalQuantity: ticketType.TotalQuantity,
+		})
+	}
+	err = outbox.AddContract(tx, s.outboxStore, newEvent.EventId.String(), contracts.Metadata{
+		Producer: producerName,
+	}, contracts.EventCreatedV1{
+		EventID:     newEvent.EventId.String(),
+		OrganizerID: newEvent.OrganizerId.String(),
+		Title:       newEvent.Title,
+		StartDate:   &newEvent.StartDate,
+		TicketTypes: ticketTypes,
 	})
 	if err != nil {
 		tx.Rollback()
-		return nil, err
-	}
-
-	err = s.outboxRepo.CreateOutboxEvent(tx, newEvent.EventId.String(), "event.created", string(eventDataJson))
-	if err != nil {
-		tx.Rollback()
-		s.logger.Error("Failed to create outbox event", zap.Error(err))
+		logger.Error("Failed to create outbox event", zap.Error(err))
 		return nil, err
 	}
 
@@ -136,13 +153,13 @@ func (s *eventService) CreateNewEvent(eventData dto.CreateNewEventDto) (*dto.Cre
 
 	// Load the complete event with relationships for the response
 	var eventWithRelations models.Event
-	err = s.db.Preload("Category").Preload("Venue").First(&eventWithRelations, "event_id = ?", newEvent.EventId).Error
+	err = s.db.WithContext(ctx).Preload("Category").Preload("Venue").First(&eventWithRelations, "event_id = ?", newEvent.EventId).Error
 	if err != nil {
-		s.logger.Warn("Failed to load event relationships, using basic event data", zap.Error(err))
+		logger.Warn("Failed to load event relationships, using basic event data", zap.Error(err))
 		eventWithRelations = newEvent
 	}
 
-	s.logger.Info("Event created and outbox event stored", zap.String("event_id", eventWithRelations.EventId.String()))
+	logger.Info("Event created and outbox event stored", zap.String("event_id", eventWithRelations.EventId.String()))
 
 	// Build response DTO
 	response := dto.CreateNewEventResponse{
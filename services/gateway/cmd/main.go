@@ -1,229 +1,107 @@
 package main
 
 import (
+	"context"
 	"gateway/internal/config"
+	"gateway/internal/health"
 	"gateway/internal/middleware"
-	"time"
+	"gateway/internal/ratelimit"
+	"gateway/internal/registry"
+	"gateway/internal/routes"
+	"gateway/internal/upstream"
+	healthcheck "github.com/whotterre/entritts/pkg/health"
+	"github.com/whotterre/entritts/pkg/metrics"
+	"github.com/whotterre/entritts/pkg/tracing"
 
 	"github.com/gofiber/contrib/fiberzap"
+	"github.com/gofiber/contrib/otelfiber/v2"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/limiter"
-	"github.com/gofiber/fiber/v2/middleware/proxy"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
-// ServiceRegistry holds the URLs for the different microservices.
-type ServiceRegistry struct {
-	services map[string]string
-	logger   *zap.Logger
-}
-
-func NewServiceRegistry(config *config.Config, logger *zap.Logger) *ServiceRegistry {
-	return &ServiceRegistry{
-		services: map[string]string{
-			"users":         "http://" + config.UserServiceHost + ":8081",
-			"events":        "http://" + config.EventServiceHost + ":8080",
-			"orders":        "http://" + config.OrderServiceHost + ":3000",
-			"tickets":       "http://" + config.TicketServiceHost + ":3000",
-			"notifications": "http://" + config.NotificationServiceHost + ":3000",
-			"payments":      "http://" + config.PaymentServiceHost + ":3000",
-		},
-		logger: logger,
-	}
-}
-
 func main() {
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 
-	rateLimiter := limiter.New(limiter.Config{
-		Max:        20,
-		Expiration: 1 * time.Second,
-	})
 	cfg := config.LoadConfig()
-	registry := NewServiceRegistry(cfg, logger)
-
-	app := fiber.New()
-
-	// Middleware
-	app.Use(fiberzap.New(fiberzap.Config{Logger: logger}))
-	app.Use(rateLimiter)
-	app.Use(cors.New())
-
-	// Public Routes Group
-	publicGroup := app.Group("/api/v1")
-
-	// Health check
-	publicGroup.Get("/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"status":  "ok",
-			"service": "gateway",
-		})
-	})
-
-	// Public auth routes
-	publicGroup.All("/auth/*", func(c *fiber.Ctx) error {
-		// Get the path after /api/v1/auth/
-		path := c.Params("*")
-
-		// Remove any leading slashes from path
-		for len(path) > 0 && path[0] == '/' {
-			path = path[1:]
-		}
-
-		// Check if user service exists in registry
-		if _, exists := registry.services["users"]; !exists {
-			logger.Error("User service not found in registry")
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-				"error": "Service configuration error",
-			})
-		}
-
-		// Build the target URL with the correct path
-		// Convert /user/ to /users/ for proper user service routing
-		if len(path) >= 5 && path[:5] == "user/" {
-			path = "users/" + path[5:]
-		}
-		targetURL := registry.services["users"] + "/" + path
-
-		logger.Info("Proxying auth request",
-			zap.String("targetURL", targetURL),
-			zap.String("originalURL", c.OriginalURL()),
-			zap.String("path", path))
-
-		// Attempt the proxy request with simple retry logic
-		var err error
-		maxRetries := 3
-		for i := 0; i < maxRetries; i++ {
-			if err = proxy.Do(c, targetURL); err == nil {
-				break
-			}
-			if i < maxRetries-1 {
-				logger.Info("Retrying proxy request",
-					zap.String("targetURL", targetURL),
-					zap.Int("attempt", i+2),
-					zap.Error(err))
-				time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
-			}
-		}
-
-		if err != nil {
-			logger.Error("Auth proxy error",
-				zap.Error(err),
-				zap.String("targetURL", targetURL))
-
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-				"error":   "Authentication service unavailable",
-				"details": err.Error(),
-			})
-		}
-		return nil
-	})
-
-	// Public user routes (for registration)
-	publicGroup.All("/users/*", func(c *fiber.Ctx) error {
-		// Get the path after /api/v1/users/
-		path := c.Params("*")
-
-		// Remove any leading slashes from path
-		for len(path) > 0 && path[0] == '/' {
-			path = path[1:]
-		}
-
-		// Check if user service exists in registry
-		if _, exists := registry.services["users"]; !exists {
-			logger.Error("User service not found in registry")
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-				"error": "Service configuration error",
-			})
-		}
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.Fatal("Failed to set up tracing", zap.Error(err))
+	}
+	defer shutdownTracing(context.Background())
 
-		// Build the target URL
-		targetURL := registry.services["users"] + "/users/" + path
+	services, err := registry.NewRegistry(cfg.Discovery, cfg.Services, logger)
+	if err != nil {
+		logger.Fatal("Invalid service registry configuration", zap.Error(err))
+	}
 
-		logger.Info("Proxying user request",
-			zap.String("targetURL", targetURL),
-			zap.String("originalURL", c.OriginalURL()),
-			zap.String("path", path))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go services.Run(ctx)
+	forwarder := upstream.NewForwarder(cfg.Proxy, services, logger)
 
-		// Attempt the proxy request with simple retry logic
-		var err error
-		maxRetries := 3
-		for i := range maxRetries {
-			if err = proxy.Do(c, targetURL); err == nil {
-				break
-			}
-			if i < maxRetries-1 {
-				logger.Info("Retrying proxy request",
-					zap.String("targetURL", targetURL),
-					zap.Int("attempt", i+2),
-					zap.Error(err))
-				time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
-			}
-		}
+	router, err := routes.NewRouter(cfg.RoutesFile, services, forwarder, logger)
+	if err != nil {
+		logger.Fatal("Failed to load routes", zap.Error(err))
+	}
+	go router.Watch(ctx, cfg.RoutesReloadInterval)
 
+	var limitStore ratelimit.Store = ratelimit.NewMemoryStore()
+	var redisClient redis.UniversalClient
+	if cfg.RateLimitStore == "redis" {
+		options, err := redis.ParseURL(cfg.RedisURL)
 		if err != nil {
-			logger.Error("User proxy error",
-				zap.Error(err),
-				zap.String("targetURL", targetURL))
-
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-				"error":   "User service unavailable",
-				"details": err.Error(),
-			})
-		}
-		return nil
-	})
-
-	// Public event routes (GET, POST, etc.)
-	publicGroup.All("/events*", func(c *fiber.Ctx) error {
-		targetURL := registry.services["events"] + "/api/v1/events" + c.Params("*")
-		if err := proxy.Do(c, targetURL); err != nil {
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-				"error": "Events service unavailable",
-			})
+			logger.Fatal("Invalid Redis URL", zap.Error(err))
 		}
-		c.Response().Header.Del(fiber.HeaderServer)
-		return nil
-	})
-
-	// Protected Routes Group
-	privateGroup := app.Group("/api/v1")
-	privateGroup.Use(middleware.RequireAuth(cfg, logger))
+		redisClient = redis.NewClient(options)
+		defer redisClient.Close()
+		limitStore = ratelimit.NewRedisStore(redisClient)
+	}
 
-	privateGroup.All("/events/*", func(c *fiber.Ctx) error {
-		targetURL := registry.services["events"] + "/api/v1/events/" + c.Params("*")
-		if err := proxy.Do(c, targetURL); err != nil {
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-				"error": "Event category service unavailable",
-			})
-		}
-		c.Response().Header.Del(fiber.HeaderServer)
-		return nil
-	})
+	app := fiber.New()
 
-	// Setup proxy routes for protected services
-	for serviceName, serviceURL := range registry.services {
-		// Skip auth routes as they're handled separately
-		if serviceName == "auth" {
-			continue
-		}
+	// Middleware
+	app.Use(middleware.RequestID())
+	app.Use(fiberzap.New(fiberzap.Config{
+		Logger: logger,
+		Fields: []string{"latency", "status", "method", "url", "requestId"},
+	}))
+	app.Use(otelfiber.Middleware())
+	// Proxied requests are labelled with the name of their route
+	app.Use(metrics.MiddlewareWithRoute(routes.MetricsLabel))
+	app.Use(cors.New())
 
-		// Closure to capture the serviceName and serviceURL
-		func(name, url string) {
-			privateGroup.All("/"+name+"/*", func(c *fiber.Ctx) error {
-				targetURL := url + c.Params("*")
-				if err := proxy.Do(c, targetURL); err != nil {
-					return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-						"error": "Service unavailable",
-					})
-				}
-				c.Response().Header.Del(fiber.HeaderServer)
-				return nil
-			})
-		}(serviceName, serviceURL)
+	// Health checks
+	// Upstream outages don't make the gateway unready; see the services view
+	checker := healthcheck.NewChecker("gateway", cfg.Discovery.Timeout)
+	if redisClient != nil {
+		checker.Add("redis", func(ctx context.Context) error {
+			return redisClient.Ping(ctx).Err()
+		})
 	}
+	app.Get("/api/v1/health", checker.Live)
+	app.Get("/api/v1/health/live", checker.Live)
+	app.Get("/api/v1/health/ready", checker.Ready)
+	healthHandler := health.NewHandler(services)
+	// The services view exposes instance URLs and errors, so it is for admins
+	app.Get("/api/v1/health/services",
+		routes.Local(routes.Route{Name: "health-services", Path: "/api/v1/health/services", Auth: true, Roles: []string{"admin"}}),
+		middleware.RequireAuth(cfg, logger),
+		middleware.RequireRoles(logger),
+		healthHandler.Services)
+
+	app.Get("/metrics", metrics.Handler())
+
+	// Everything else is routed by the routes file. Clients are limited by IP
+	// first, so requests that fail routing or auth still count.
+	app.Use(ratelimit.PerIP(limitStore, cfg.IPRateLimit, logger))
+	app.Use(router.Match)
+	app.Use(middleware.RequireAuth(cfg, logger))
+	app.Use(middleware.RequireRoles(logger))
+	app.Use(ratelimit.New(limitStore, cfg.RateLimit, logger))
+	app.All("/*", router.Proxy)
 
 	logger.Fatal("Gateway server crashed", zap.Error(app.Listen(cfg.GatewayPort)))
 }
@@ -1,7 +1,14 @@
 package config
 
 import (
+	"gateway/internal/ratelimit"
+	"gateway/internal/registry"
+	"gateway/internal/upstream"
+	"github.com/whotterre/entritts/pkg/tracing"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,12 +22,30 @@ type Config struct {
 	TicketServiceHost       string
 	NotificationServiceHost string
 	PaymentServiceHost      string
+	// Services lists the instances of every upstream; each defaults to the
+	// single instance on its *_HOST above
+	Services  []registry.ServiceConfig
+	Discovery registry.Config
+	Proxy     upstream.Config
+	// RoutesFile is the YAML or JSON route table, checked for changes every
+	// RoutesReloadInterval
+	RoutesFile           string
+	RoutesReloadInterval time.Duration
+	// RateLimit applies to routes without a rate limit of their own
+	RateLimit ratelimit.Limit
+	// IPRateLimit applies to every request from a client IP, before routing
+	// and authentication
+	IPRateLimit ratelimit.Limit
+	// RateLimitStore is memory or redis; use redis with several replicas
+	RateLimitStore string
+	RedisURL       string
+	Tracing        tracing.Config
 }
 
 func LoadConfig() *Config {
 	_ = godotenv.Load()
 
-	return &Config{
+	cfg := &Config{
 		GatewayPort:             getEnv("GATEWAY_PORT", "3000"),
 		PasetoSecret:            getEnv("PASETO_SECRET", "'0nb4W4x--rC60r9bDPiIAbcyXHTsRQ_'"),
 		UserServiceHost:         getEnv("USER_SERVICE_HOST", "user-service"),
@@ -29,6 +54,61 @@ func LoadConfig() *Config {
 		TicketServiceHost:       getEnv("TICKET_SERVICE_HOST", "ticket-service"),
 		NotificationServiceHost: getEnv("NOTIF_SERVICE_HOST", "notif-service"),
 		PaymentServiceHost:      getEnv("PAYMENT_SERVICE_HOST", "payment-service"),
+		RoutesFile:              getEnv("ROUTES_FILE", "routes.yaml"),
+		RoutesReloadInterval:    getEnvAsDuration("ROUTES_RELOAD_INTERVAL", 5*time.Second),
+		RateLimit: ratelimit.Limit{
+			Requests: getEnvAsInt("RATE_LIMIT_REQUESTS", 20),
+			Window:   getEnvAsDuration("RATE_LIMIT_WINDOW", time.Second),
+			Burst:    getEnvAsInt("RATE_LIMIT_BURST", 0),
+		},
+		IPRateLimit: ratelimit.Limit{
+			Requests: getEnvAsInt("IP_RATE_LIMIT_REQUESTS", 50),
+			Window:   getEnvAsDuration("IP_RATE_LIMIT_WINDOW", time.Second),
+			Burst:    getEnvAsInt("IP_RATE_LIMIT_BURST", 0),
+		},
+		RateLimitStore: getEnv("RATE_LIMIT_STORE", "memory"),
+		RedisURL:       getEnv("REDIS_URL", "redis://localhost:6379/0"),
+		Tracing: tracing.Config{
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "gateway"),
+			Exporter:    getEnv("OTEL_TRACES_EXPORTER", tracing.ExporterNone),
+		},
+		Discovery: registry.Config{
+			Strategy:           getEnv("LOAD_BALANCER", registry.RoundRobin),
+			Interval:           getEnvAsDuration("HEALTH_CHECK_INTERVAL", 10*time.Second),
+			Timeout:            getEnvAsDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+			UnhealthyThreshold: getEnvAsInt("HEALTH_CHECK_UNHEALTHY_THRESHOLD", 2),
+			HealthyThreshold:   getEnvAsInt("HEALTH_CHECK_HEALTHY_THRESHOLD", 1),
+		},
+		Proxy: upstream.Config{
+			Timeout:                 getEnvAsDuration("PROXY_TIMEOUT", 10*time.Second),
+			MaxRetries:              getEnvAsInt("PROXY_MAX_RETRIES", 2),
+			RetryBackoff:            getEnvAsDuration("PROXY_RETRY_BACKOFF", 100*time.Millisecond),
+			RetryBudgetPercent:      getEnvAsInt("RETRY_BUDGET_PERCENT", 20),
+			RetryBudgetBurst:        getEnvAsInt("RETRY_BUDGET_BURST", 10),
+			BreakerFailureThreshold: getEnvAsInt("BREAKER_FAILURE_THRESHOLD", 5),
+			BreakerOpenTimeout:      getEnvAsDuration("BREAKER_OPEN_TIMEOUT", 30*time.Second),
+			BreakerHalfOpenRequests: getEnvAsInt("BREAKER_HALF_OPEN_REQUESTS", 1),
+		},
+	}
+
+	cfg.Services = []registry.ServiceConfig{
+		serviceConfig("users", "USER_SERVICE", "http://"+cfg.UserServiceHost+":8081", "/health"),
+		serviceConfig("events", "EVENT_SERVICE", "http://"+cfg.EventServiceHost+":8080", "/api/v1/health"),
+		serviceConfig("orders", "ORDER_SERVICE", "http://"+cfg.OrderServiceHost+":3000", "/health"),
+		serviceConfig("tickets", "TICKET_SERVICE", "http://"+cfg.TicketServiceHost+":3000", "/health"),
+		serviceConfig("notifications", "NOTIF_SERVICE", "http://"+cfg.NotificationServiceHost+":3000", "/health"),
+		serviceConfig("payments", "PAYMENT_SERVICE", "http://"+cfg.PaymentServiceHost+":3000", "/health"),
+	}
+	return cfg
+}
+
+// serviceConfig reads <prefix>_URLS, a comma separated list of instances, and
+// <prefix>_HEALTH_PATH
+func serviceConfig(name, prefix, defaultURL, defaultHealthPath string) registry.ServiceConfig {
+	return registry.ServiceConfig{
+		Name:       name,
+		URLs:       getEnvAsList(prefix+"_URLS", []string{defaultURL}),
+		HealthPath: getEnv(prefix+"_HEALTH_PATH", defaultHealthPath),
 	}
 }
 
@@ -38,3 +118,38 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvAsInt(key string, defaultValue int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if intVal, err := strconv.Atoi(value); err == nil {
+			return intVal
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsList(key string, defaultValue []string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	if len(list) == 0 {
+		return defaultValue
+	}
+	return list
+}
@@ -0,0 +1,73 @@
+// Package health serves an aggregate view of every upstream's health, as seen
+// by the registry's checks. The gateway's own liveness and readiness come from
+// pkg/health like every other service's.
+package health
+
+import (
+	"gateway/internal/registry"
+
+	"github.com/gofiber/fiber/v2"
+	healthcheck "github.com/whotterre/entritts/pkg/health"
+)
+
+// StatusDegraded means some, but not all, instances of an upstream are healthy
+const StatusDegraded = "degraded"
+
+const serviceName = "gateway"
+
+// ServiceHealth summarises the instances of one upstream
+type ServiceHealth struct {
+	Status    string                    `json:"status"`
+	Healthy   int                       `json:"healthy"`
+	Instances []registry.InstanceStatus `json:"instances"`
+}
+
+// Report is the body of the services view
+type Report struct {
+	Status   string                   `json:"status"`
+	Service  string                   `json:"service"`
+	Services map[string]ServiceHealth `json:"services"`
+}
+
+type Handler struct {
+	registry *registry.Registry
+}
+
+func NewHandler(registry *registry.Registry) *Handler {
+	return &Handler{registry: registry}
+}
+
+// Services reports the health of every registered upstream from the latest
+// round of instance checks, including each instance's own readiness report.
+// It answers 503 when any upstream has no healthy instance.
+func (h *Handler) Services(c *fiber.Ctx) error {
+	report := Report{
+		Status:   healthcheck.StatusUp,
+		Service:  serviceName,
+		Services: make(map[string]ServiceHealth),
+	}
+	for name, instances := range h.registry.Status() {
+		service := ServiceHealth{Status: healthcheck.StatusUp, Instances: instances}
+		for _, instance := range instances {
+			if instance.Healthy {
+				service.Healthy++
+			}
+		}
+		switch {
+		case service.Healthy == 0:
+			service.Status = healthcheck.StatusDown
+			report.Status = healthcheck.StatusDown
+		case service.Healthy < len(instances):
+			service.Status = StatusDegraded
+			if report.Status == healthcheck.StatusUp {
+				report.Status = StatusDegraded
+			}
+		}
+		report.Services[name] = service
+	}
+
+	if report.Status == healthcheck.StatusDown {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(report)
+	}
+	return c.JSON(report)
+}
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"gateway/internal/config"
+	"gateway/internal/routes"
+	"slices"
 	"strings"
 	"time"
 
@@ -11,10 +13,11 @@ import (
 	"go.uber.org/zap"
 )
 
-// AuthMiddleware performs authentication with Bearer Auth
+// AuthMiddleware performs authentication with Bearer Auth on routes that
+// require it
 func RequireAuth(config *config.Config, logger *zap.Logger) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		if isPublicRoute(c.Path()) {
+		if route, ok := routes.FromContext(c); !ok || !route.Auth {
 			return c.Next()
 		}
 
@@ -60,6 +63,14 @@ func RequireAuth(config *config.Config, logger *zap.Logger) fiber.Handler {
 			})
 		}
 
+		// Purpose bound tokens (e.g. MFA challenges) are not access tokens
+		if purpose, ok := jsonToken["purpose"].(string); ok && purpose != "" {
+			logger.Warn("Purpose bound token used as access token", zap.String("purpose", purpose))
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid token",
+			})
+		}
+
 		userIDStr, ok := jsonToken["user_id"].(string)
 		if !ok {
 			logger.Warn("Invalid user ID in token")
@@ -77,16 +88,41 @@ func RequireAuth(config *config.Config, logger *zap.Logger) fiber.Handler {
 		}
 
 		c.Locals("userID", userID)
+		c.Locals("roles", tokenRoles(jsonToken))
 		return c.Next()
 	}
 }
 
-func isPublicRoute(path string) bool {
-	publicRoutes := []string{"/health", "/api/v1/users/login", "/api/v1/users/register"}
-	for _, route := range publicRoutes {
-		if path == route {
-			return true
+// RequireRoles only lets through users holding at least one of the matched
+// route's roles
+func RequireRoles(logger *zap.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		route, ok := routes.FromContext(c)
+		if !ok || len(route.Roles) == 0 {
+			return c.Next()
+		}
+
+		userRoles, _ := c.Locals("roles").([]string)
+		for _, role := range route.Roles {
+			if slices.Contains(userRoles, role) {
+				return c.Next()
+			}
+		}
+
+		logger.Warn("Insufficient role", zap.String("route", route.Name), zap.Any("user_id", c.Locals("userID")))
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Insufficient permissions",
+		})
+	}
+}
+
+func tokenRoles(jsonToken map[string]interface{}) []string {
+	claim, _ := jsonToken["roles"].([]interface{})
+	roles := make([]string, 0, len(claim))
+	for _, role := range claim {
+		if name, ok := role.(string); ok {
+			roles = append(roles, name)
 		}
 	}
-	return false
+	return roles
 }
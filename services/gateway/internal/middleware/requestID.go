@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/whotterre/entritts/pkg/requestid"
+)
+
+// RequestID assigns every request an X-Request-ID and sends it upstream, so the
+// services' logs and messages can be tied back to the request. A well formed
+// ID sent by the client is kept.
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(fiber.HeaderXRequestID)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		c.Request().Header.Set(fiber.HeaderXRequestID, id)
+		c.Locals("requestID", id)
+		c.Set(fiber.HeaderXRequestID, id)
+
+		err := c.Next()
+		// Proxying replaces the response headers
+		c.Set(fiber.HeaderXRequestID, id)
+		return err
+	}
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit is a token bucket refilling Requests tokens every Window and holding
+// at most Burst of them
+type Limit struct {
+	Requests int
+	Window   time.Duration
+	// Burst defaults to Requests
+	Burst int
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until a token is available, when not allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets. Take removes one token from the bucket at key,
+// creating a full bucket if there is none.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// perSecond is the refill rate
+func (l Limit) perSecond() float64 {
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+// result describes a bucket left holding tokens after a take
+func (l Limit) result(allowed bool, tokens float64) Result {
+	rate := l.perSecond()
+	result := Result{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((l.capacity() - tokens) / rate),
+	}
+	if !allowed {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Max(0, s) * float64(time.Second))
+}
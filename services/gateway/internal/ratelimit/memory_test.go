@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreBurst(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Requests: 1, Window: time.Hour, Burst: 3}
+
+	for i := range 3 {
+		result, err := store.Take(context.Background(), "client", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Allowed {
+			t.Fatalf("take %d: expected to be allowed within the burst", i+1)
+		}
+		if want := 2 - i; result.Remaining != want {
+			t.Fatalf("take %d: expected %d remaining, got %d", i+1, want, result.Remaining)
+		}
+	}
+
+	result, err := store.Take(context.Background(), "client", limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Allowed {
+		t.Fatal("expected the bucket to be empty after the burst")
+	}
+	if result.RetryAfter <= 0 || result.RetryAfter > time.Hour {
+		t.Fatalf("expected a retry within the window, got %v", result.RetryAfter)
+	}
+
+	// Buckets are per key
+	if result, _ := store.Take(context.Background(), "other", limit); !result.Allowed {
+		t.Fatal("expected another key to have its own bucket")
+	}
+}
+
+func TestMemoryStoreRefill(t *testing.T) {
+	store := NewMemoryStore()
+	// One token every 20ms
+	limit := Limit{Requests: 5, Window: 100 * time.Millisecond, Burst: 1}
+
+	if result, _ := store.Take(context.Background(), "client", limit); !result.Allowed {
+		t.Fatal("expected the first take to be allowed")
+	}
+	result, _ := store.Take(context.Background(), "client", limit)
+	if result.Allowed {
+		t.Fatal("expected the bucket to be empty")
+	}
+
+	time.Sleep(result.RetryAfter + 5*time.Millisecond)
+	if result, _ := store.Take(context.Background(), "client", limit); !result.Allowed {
+		t.Fatal("expected a token after RetryAfter")
+	}
+
+	// Refilling stops at Burst
+	time.Sleep(60 * time.Millisecond)
+	if result, _ := store.Take(context.Background(), "client", limit); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("expected the bucket to hold at most one token, got %+v", result)
+	}
+}
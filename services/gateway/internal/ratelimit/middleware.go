@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"fmt"
+	"gateway/internal/routes"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// New limits each client, the authenticated user or else the client IP, with
+// the matched route's rate limit or the default one. It must run after
+// authentication so the user is known, which leaves requests rejected before
+// then to PerIP. When the store fails, requests are let through rather than
+// turned away.
+func New(store Store, defaultLimit Limit, logger *zap.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		scope, limit := "default", defaultLimit
+		if route, ok := routes.FromContext(c); ok && route.RateLimit != nil {
+			scope = "route:" + route.Name
+			limit = Limit{
+				Requests: route.RateLimit.Requests,
+				Window:   time.Duration(route.RateLimit.Window),
+				Burst:    route.RateLimit.Burst,
+			}
+		}
+		if limit.Requests <= 0 || limit.Window <= 0 {
+			return c.Next()
+		}
+
+		result, err := store.Take(c.UserContext(), scope+":"+clientKey(c), limit)
+		if err != nil {
+			logger.Warn("Rate limit store unavailable, allowing request", zap.Error(err))
+			return c.Next()
+		}
+
+		if !result.Allowed {
+			return reject(c, limit, result)
+		}
+
+		// Set afterwards, as proxying replaces the response headers
+		err = c.Next()
+		setHeaders(c, limit, result)
+		return err
+	}
+}
+
+// PerIP limits every request by client IP before routing and authentication,
+// so requests answered with 401, 403, 404 or 405 are counted too. It only sets
+// the RateLimit headers when it turns a request away; otherwise New sets them
+// for the route's limit.
+func PerIP(store Store, limit Limit, logger *zap.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if limit.Requests <= 0 || limit.Window <= 0 {
+			return c.Next()
+		}
+
+		result, err := store.Take(c.UserContext(), "global:ip:"+c.IP(), limit)
+		if err != nil {
+			logger.Warn("Rate limit store unavailable, allowing request", zap.Error(err))
+			return c.Next()
+		}
+		if !result.Allowed {
+			return reject(c, limit, result)
+		}
+		return c.Next()
+	}
+}
+
+func reject(c *fiber.Ctx, limit Limit, result Result) error {
+	setHeaders(c, limit, result)
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error": "Too many requests",
+	})
+}
+
+func setHeaders(c *fiber.Ctx, limit Limit, result Result) {
+	c.Set("RateLimit-Limit", strconv.Itoa(int(limit.capacity())))
+	c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+	c.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Window)))
+}
+
+func clientKey(c *fiber.Ctx) string {
+	if userID := c.Locals("userID"); userID != nil {
+		return fmt.Sprintf("user:%v", userID)
+	}
+	return "ip:" + c.IP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
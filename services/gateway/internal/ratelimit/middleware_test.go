@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+func TestRateLimitHeaders(t *testing.T) {
+	app := fiber.New()
+	app.Use(New(NewMemoryStore(), Limit{Requests: 2, Window: time.Minute}, zap.NewNop()))
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	tests := []struct {
+		status     int
+		remaining  string
+		retryAfter string
+	}{
+		{status: fiber.StatusOK, remaining: "1"},
+		{status: fiber.StatusOK, remaining: "0"},
+		{status: fiber.StatusTooManyRequests, remaining: "0", retryAfter: "30"},
+	}
+	for i, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Fatalf("request %d: expected status %d, got %d", i+1, tt.status, resp.StatusCode)
+		}
+
+		headers := map[string]string{
+			"RateLimit-Limit":     "2",
+			"RateLimit-Remaining": tt.remaining,
+			"RateLimit-Policy":    "2;w=60",
+			"Retry-After":         tt.retryAfter,
+		}
+		for name, want := range headers {
+			if got := resp.Header.Get(name); got != want {
+				t.Fatalf("request %d: expected %s %q, got %q", i+1, name, want, got)
+			}
+		}
+		if reset := resp.Header.Get("RateLimit-Reset"); reset == "" || reset == "0" {
+			t.Fatalf("request %d: expected a RateLimit-Reset, got %q", i+1, reset)
+		}
+	}
+}
+
+func TestPerIPCountsRejectedRequests(t *testing.T) {
+	app := fiber.New()
+	app.Use(PerIP(NewMemoryStore(), Limit{Requests: 1, Window: time.Minute}, zap.NewNop()))
+	// Stands in for routing or auth turning the request away
+	app.Use(func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing authorization header"})
+	})
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", resp.StatusCode)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("expected the rejected request to count, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Fatal("expected a Retry-After header")
+	}
+}
@@ -0,0 +1,51 @@
+package registry
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+const (
+	RoundRobin       = "round_robin"
+	LeastConnections = "least_connections"
+)
+
+// Balancer picks one of a service's healthy instances for a request
+type Balancer interface {
+	Pick(instances []*Instance) *Instance
+}
+
+// NewBalancer returns the balancer for a strategy name, defaulting to round robin
+func NewBalancer(strategy string) (Balancer, error) {
+	switch strategy {
+	case "", RoundRobin:
+		return &roundRobin{}, nil
+	case LeastConnections:
+		return leastConnections{}, nil
+	}
+	return nil, fmt.Errorf("unknown load balancing strategy %q", strategy)
+}
+
+type roundRobin struct {
+	next atomic.Uint64
+}
+
+func (b *roundRobin) Pick(instances []*Instance) *Instance {
+	if len(instances) == 0 {
+		return nil
+	}
+	n := b.next.Add(1) - 1
+	return instances[n%uint64(len(instances))]
+}
+
+type leastConnections struct{}
+
+func (leastConnections) Pick(instances []*Instance) *Instance {
+	var picked *Instance
+	for _, instance := range instances {
+		if picked == nil || instance.Active() < picked.Active() {
+			picked = instance
+		}
+	}
+	return picked
+}
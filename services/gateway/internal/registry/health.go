@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxReportSize caps how much of a health response is read and kept
+const maxReportSize = 16 << 10
+
+func (r *Registry) check(ctx context.Context, service *Service, instance *Instance) {
+	result := r.probe(ctx, instance.URL+service.HealthPath)
+	if !instance.record(result, r.cfg) {
+		return
+	}
+
+	if instance.Healthy() {
+		r.logger.Info("Instance is healthy again, adding it back",
+			zap.String("service", service.Name),
+			zap.String("instance", instance.URL))
+		return
+	}
+	r.logger.Warn("Instance failed its health checks, removing it",
+		zap.String("service", service.Name),
+		zap.String("instance", instance.URL),
+		zap.Error(result.err))
+}
+
+// probeResult is the outcome of one health check
+type probeResult struct {
+	err     error
+	latency time.Duration
+	// report is the body of the response, when it was JSON
+	report json.RawMessage
+}
+
+func (r *Registry) probe(ctx context.Context, url string) probeResult {
+	start := time.Now()
+	report, err := r.fetch(ctx, url)
+	return probeResult{err: err, latency: time.Since(start), report: report}
+}
+
+// fetch requests an instance's health endpoint, returning the body if it is
+// JSON and an error unless the status is 2xx
+func (r *Registry) fetch(ctx context.Context, url string) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var report json.RawMessage
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxReportSize))
+	if json.Valid(body) {
+		report = body
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return report, fmt.Errorf("health check returned %d", resp.StatusCode)
+	}
+	return report, nil
+}
@@ -0,0 +1,109 @@
+package registry
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Instance is one running copy of a service
+type Instance struct {
+	URL string
+
+	healthy atomic.Bool
+	active  atomic.Int64
+
+	mu          sync.Mutex
+	successes   int
+	failures    int
+	lastCheck   time.Time
+	lastError   string
+	lastLatency time.Duration
+	lastReport  json.RawMessage
+}
+
+func newInstance(url string) *Instance {
+	instance := &Instance{URL: url}
+	// Instances take traffic until the first check says otherwise
+	instance.healthy.Store(true)
+	return instance
+}
+
+// Healthy reports whether the instance passed its recent health checks
+func (i *Instance) Healthy() bool {
+	return i.healthy.Load()
+}
+
+// Active is the number of requests currently forwarded to the instance
+func (i *Instance) Active() int64 {
+	return i.active.Load()
+}
+
+// Acquire counts a request against the instance; call the returned function
+// once the request has finished
+func (i *Instance) Acquire() func() {
+	i.active.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() { i.active.Add(-1) })
+	}
+}
+
+// InstanceStatus is a snapshot of an instance's health
+type InstanceStatus struct {
+	URL       string    `json:"url"`
+	Healthy   bool      `json:"healthy"`
+	Active    int64     `json:"active"`
+	LastCheck time.Time `json:"last_check"`
+	LastError string    `json:"last_error,omitempty"`
+	// LatencyMs is how long the last health check took
+	LatencyMs float64 `json:"latency_ms"`
+	// Report is the instance's own answer to the last check, when it was JSON
+	Report json.RawMessage `json:"report,omitempty"`
+}
+
+// Status returns a snapshot of the instance
+func (i *Instance) Status() InstanceStatus {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return InstanceStatus{
+		URL:       i.URL,
+		Healthy:   i.Healthy(),
+		Active:    i.Active(),
+		LastCheck: i.lastCheck,
+		LastError: i.lastError,
+		LatencyMs: float64(i.lastLatency.Microseconds()) / 1000,
+		Report:    i.lastReport,
+	}
+}
+
+// record applies a health check result, flipping the instance's state once
+// enough consecutive checks agree. It reports whether the state changed.
+func (i *Instance) record(result probeResult, cfg Config) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.lastCheck = time.Now()
+	i.lastLatency = result.latency
+	i.lastReport = result.report
+	if result.err != nil {
+		i.lastError = result.err.Error()
+		i.successes = 0
+		i.failures++
+		if i.Healthy() && i.failures >= cfg.UnhealthyThreshold {
+			i.healthy.Store(false)
+			return true
+		}
+		return false
+	}
+
+	i.lastError = ""
+	i.failures = 0
+	i.successes++
+	if !i.Healthy() && i.successes >= cfg.HealthyThreshold {
+		i.healthy.Store(true)
+		return true
+	}
+	return false
+}
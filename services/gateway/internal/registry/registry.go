@@ -0,0 +1,196 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrUnknownService     = errors.New("service is not registered")
+	ErrNoHealthyInstances = errors.New("no healthy instances")
+)
+
+// ServiceConfig lists the instances of one service
+type ServiceConfig struct {
+	Name string
+	URLs []string
+	// HealthPath is requested on every instance, defaulting to /health
+	HealthPath string
+}
+
+// Config controls health checking and load balancing
+type Config struct {
+	// Strategy is round_robin or least_connections
+	Strategy string
+	// Interval between health checks of each instance
+	Interval time.Duration
+	// Timeout for a single health check request
+	Timeout time.Duration
+	// Consecutive failed checks before an instance stops taking traffic
+	UnhealthyThreshold int
+	// Consecutive passed checks before it takes traffic again
+	HealthyThreshold int
+}
+
+// DefaultConfig returns the settings used when none are configured
+func DefaultConfig() Config {
+	return Config{
+		Strategy:           RoundRobin,
+		Interval:           10 * time.Second,
+		Timeout:            2 * time.Second,
+		UnhealthyThreshold: 2,
+		HealthyThreshold:   1,
+	}
+}
+
+// Service is a named group of instances behind one balancer
+type Service struct {
+	Name       string
+	HealthPath string
+	instances  []*Instance
+	balancer   Balancer
+}
+
+// Healthy returns the instances currently taking traffic
+func (s *Service) Healthy() []*Instance {
+	healthy := make([]*Instance, 0, len(s.instances))
+	for _, instance := range s.instances {
+		if instance.Healthy() {
+			healthy = append(healthy, instance)
+		}
+	}
+	return healthy
+}
+
+// Registry knows every instance of every service the gateway forwards to and
+// keeps track of which of them are healthy
+type Registry struct {
+	cfg      Config
+	services map[string]*Service
+	client   *http.Client
+	logger   *zap.Logger
+}
+
+func NewRegistry(cfg Config, services []ServiceConfig, logger *zap.Logger) (*Registry, error) {
+	defaults := DefaultConfig()
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaults.Interval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	if cfg.UnhealthyThreshold <= 0 {
+		cfg.UnhealthyThreshold = defaults.UnhealthyThreshold
+	}
+	if cfg.HealthyThreshold <= 0 {
+		cfg.HealthyThreshold = defaults.HealthyThreshold
+	}
+
+	registry := &Registry{
+		cfg:      cfg,
+		services: make(map[string]*Service, len(services)),
+		client:   &http.Client{Timeout: cfg.Timeout},
+		logger:   logger,
+	}
+	for _, sc := range services {
+		if len(sc.URLs) == 0 {
+			return nil, fmt.Errorf("service %s has no instances", sc.Name)
+		}
+		balancer, err := NewBalancer(cfg.Strategy)
+		if err != nil {
+			return nil, err
+		}
+		healthPath := sc.HealthPath
+		if healthPath == "" {
+			healthPath = "/health"
+		}
+		service := &Service{
+			Name:       sc.Name,
+			HealthPath: healthPath,
+			balancer:   balancer,
+		}
+		for _, url := range sc.URLs {
+			service.instances = append(service.instances, newInstance(strings.TrimRight(url, "/")))
+		}
+		registry.services[sc.Name] = service
+	}
+	return registry, nil
+}
+
+// Names returns the registered service names in order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.services))
+	for name := range r.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has reports whether a service is registered
+func (r *Registry) Has(name string) bool {
+	_, ok := r.services[name]
+	return ok
+}
+
+// Next picks a healthy instance of the named service
+func (r *Registry) Next(name string) (*Instance, error) {
+	service, ok := r.services[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownService, name)
+	}
+	instance := service.balancer.Pick(service.Healthy())
+	if instance == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoHealthyInstances, name)
+	}
+	return instance, nil
+}
+
+// Status returns a snapshot of every instance, keyed by service name
+func (r *Registry) Status() map[string][]InstanceStatus {
+	status := make(map[string][]InstanceStatus, len(r.services))
+	for name, service := range r.services {
+		for _, instance := range service.instances {
+			status[name] = append(status[name], instance.Status())
+		}
+	}
+	return status
+}
+
+// Run health checks every instance until ctx is cancelled
+func (r *Registry) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.CheckAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll health checks every instance once, concurrently
+func (r *Registry) CheckAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, service := range r.services {
+		for _, instance := range service.instances {
+			wg.Add(1)
+			go func(service *Service, instance *Instance) {
+				defer wg.Done()
+				r.check(ctx, service, instance)
+			}(service, instance)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,143 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is the layout of the routes file
+type File struct {
+	Routes []Route `yaml:"routes" json:"routes"`
+}
+
+// Route maps requests matching a path pattern onto an upstream service
+type Route struct {
+	Name string `yaml:"name" json:"name"`
+	// Path is matched exactly, or as a prefix when it ends in *
+	Path string `yaml:"path" json:"path"`
+	// Methods the route accepts; empty accepts every method
+	Methods []string `yaml:"methods" json:"methods"`
+	// Upstream is the name of a service in the registry
+	Upstream string `yaml:"upstream" json:"upstream"`
+	// Rewrite rules are applied to the request path in order
+	Rewrite []Rewrite `yaml:"rewrite" json:"rewrite"`
+	// Auth requires a valid access token
+	Auth bool `yaml:"auth" json:"auth"`
+	// Roles, when set, requires the token to hold at least one of them
+	Roles     []string   `yaml:"roles" json:"roles"`
+	RateLimit *RateLimit `yaml:"rate_limit" json:"rate_limit"`
+	// Timeout overrides the proxy timeout for the route
+	Timeout Duration `yaml:"timeout" json:"timeout"`
+}
+
+// Rewrite replaces matches of a regular expression in the request path
+type Rewrite struct {
+	Pattern     string `yaml:"pattern" json:"pattern"`
+	Replacement string `yaml:"replacement" json:"replacement"`
+}
+
+// RateLimit allows each client of a route Requests per Window, with bursts of
+// up to Burst requests (defaulting to Requests)
+type RateLimit struct {
+	Requests int      `yaml:"requests" json:"requests"`
+	Window   Duration `yaml:"window" json:"window"`
+	Burst    int      `yaml:"burst" json:"burst"`
+}
+
+// Duration reads "5s" style durations from YAML and JSON
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return d.parse(value)
+}
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	var value string
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	return d.parse(value)
+}
+
+func (d *Duration) parse(value string) error {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// compiledRoute is a Route checked and ready to serve requests
+type compiledRoute struct {
+	Route
+	prefix   bool
+	pattern  string
+	rewrites []*regexp.Regexp
+}
+
+func compile(route Route) (*compiledRoute, error) {
+	if !strings.HasPrefix(route.Path, "/") {
+		return nil, fmt.Errorf("path %q must start with /", route.Path)
+	}
+	if route.Upstream == "" {
+		return nil, fmt.Errorf("no upstream")
+	}
+	if len(route.Roles) > 0 && !route.Auth {
+		return nil, fmt.Errorf("roles require auth")
+	}
+
+	compiled := &compiledRoute{Route: route, pattern: route.Path}
+	if strings.HasSuffix(route.Path, "*") {
+		compiled.prefix = true
+		compiled.pattern = strings.TrimSuffix(route.Path, "*")
+	}
+	for i, method := range route.Methods {
+		compiled.Methods[i] = strings.ToUpper(method)
+	}
+	for _, rewrite := range route.Rewrite {
+		re, err := regexp.Compile(rewrite.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rewrite %q: %w", rewrite.Pattern, err)
+		}
+		compiled.rewrites = append(compiled.rewrites, re)
+	}
+	if limit := route.RateLimit; limit != nil {
+		if limit.Requests <= 0 || limit.Window <= 0 || limit.Burst < 0 {
+			return nil, fmt.Errorf("rate limit needs positive requests and window")
+		}
+	}
+	return compiled, nil
+}
+
+func (r *compiledRoute) matchesPath(path string) bool {
+	if r.prefix {
+		return strings.HasPrefix(path, r.pattern)
+	}
+	return path == r.pattern
+}
+
+func (r *compiledRoute) allowsMethod(method string) bool {
+	return len(r.Methods) == 0 || slices.Contains(r.Methods, method)
+}
+
+// target is the path to request from the upstream
+func (r *compiledRoute) target(path string) string {
+	for i, re := range r.rewrites {
+		path = re.ReplaceAllString(path, r.Rewrite[i].Replacement)
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
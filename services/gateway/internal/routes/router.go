@@ -0,0 +1,202 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gateway/internal/registry"
+	"gateway/internal/upstream"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+const routeKey = "route"
+
+// Router matches requests against the routes file and proxies them upstream.
+// The routes can be swapped out at runtime without restarting the gateway.
+type Router struct {
+	path      string
+	registry  *registry.Registry
+	forwarder *upstream.Forwarder
+	logger    *zap.Logger
+
+	routes  atomic.Pointer[[]*compiledRoute]
+	modTime time.Time
+}
+
+// NewRouter loads the routes file at path, failing if it is missing or invalid
+func NewRouter(path string, registry *registry.Registry, forwarder *upstream.Forwarder, logger *zap.Logger) (*Router, error) {
+	router := &Router{
+		path:      path,
+		registry:  registry,
+		forwarder: forwarder,
+		logger:    logger,
+	}
+	if err := router.Reload(); err != nil {
+		return nil, err
+	}
+	return router, nil
+}
+
+// Reload reads the routes file again. The current routes stay in place if
+// the file can't be read or fails validation.
+func (r *Router) Reload() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+
+	var file File
+	if filepath.Ext(r.path) == ".json" {
+		err = json.Unmarshal(data, &file)
+	} else {
+		err = yaml.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", r.path, err)
+	}
+
+	compiled := make([]*compiledRoute, 0, len(file.Routes))
+	for i, route := range file.Routes {
+		if route.Name == "" {
+			route.Name = route.Path
+		}
+		c, err := compile(route)
+		if err != nil {
+			return fmt.Errorf("route %d (%s): %w", i, route.Name, err)
+		}
+		if !r.registry.Has(route.Upstream) {
+			return fmt.Errorf("route %d (%s): %w: %s", i, route.Name, registry.ErrUnknownService, route.Upstream)
+		}
+		compiled = append(compiled, c)
+	}
+
+	r.routes.Store(&compiled)
+	r.modTime = info.ModTime()
+	r.logger.Info("Loaded routes", zap.String("file", r.path), zap.Int("routes", len(compiled)))
+	return nil
+}
+
+// Watch reloads the routes file whenever it changes, checking every interval
+// until ctx is cancelled
+func (r *Router) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(r.path)
+		if err != nil {
+			r.logger.Warn("Failed to check routes file", zap.String("file", r.path), zap.Error(err))
+			continue
+		}
+		if info.ModTime().Equal(r.modTime) {
+			continue
+		}
+		if err := r.Reload(); err != nil {
+			// Don't try the same broken file again until it changes
+			r.modTime = info.ModTime()
+			r.logger.Error("Failed to reload routes, keeping the current ones",
+				zap.String("file", r.path), zap.Error(err))
+		}
+	}
+}
+
+// Match finds the first route for the request, in file order, and stores it
+// for the middleware that follows
+func (r *Router) Match(c *fiber.Ctx) error {
+	path, method := c.Path(), c.Method()
+	pathMatched := false
+	for _, route := range *r.routes.Load() {
+		if !route.matchesPath(path) {
+			continue
+		}
+		pathMatched = true
+		if route.allowsMethod(method) {
+			c.Locals(routeKey, route)
+			return c.Next()
+		}
+	}
+
+	if pathMatched {
+		return c.Status(fiber.StatusMethodNotAllowed).JSON(fiber.Map{
+			"error": "Method not allowed",
+		})
+	}
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"error": "Route not found",
+	})
+}
+
+// Proxy forwards the request to the matched route's upstream
+func (r *Router) Proxy(c *fiber.Ctx) error {
+	route, ok := c.Locals(routeKey).(*compiledRoute)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Route not found",
+		})
+	}
+
+	target := route.target(c.Path())
+	if query := c.Request().URI().QueryString(); len(query) > 0 {
+		target += "?" + string(query)
+	}
+	r.logger.Debug("Proxying request",
+		zap.String("route", route.Name),
+		zap.String("upstream", route.Upstream),
+		zap.String("originalURL", c.OriginalURL()),
+		zap.String("target", target))
+
+	if route.Timeout > 0 {
+		return r.forwarder.ForwardTimeout(c, route.Upstream, target, time.Duration(route.Timeout))
+	}
+	return r.forwarder.Forward(c, route.Upstream, target)
+}
+
+// FromContext returns the route matched for the request
+func FromContext(c *fiber.Ctx) (Route, bool) {
+	route, ok := c.Locals(routeKey).(*compiledRoute)
+	if !ok {
+		return Route{}, false
+	}
+	return route.Route, true
+}
+
+// MetricsLabel names the route that handled the request for metrics: the name
+// from the routes file, or the path of one of the gateway's own endpoints.
+// It is empty for requests nothing handled.
+func MetricsLabel(c *fiber.Ctx) string {
+	if route, ok := FromContext(c); ok {
+		return route.Name
+	}
+	// Middleware is mounted at / and the proxy at /*
+	if path := c.Route().Path; path != "/" && path != "/*" {
+		return path
+	}
+	return ""
+}
+
+// Local attaches route to requests the gateway serves itself, so the
+// middleware that follows Match applies its auth and roles to them
+func Local(route Route) fiber.Handler {
+	compiled := &compiledRoute{Route: route, pattern: route.Path}
+	return func(c *fiber.Ctx) error {
+		c.Locals(routeKey, compiled)
+		return c.Next()
+	}
+}
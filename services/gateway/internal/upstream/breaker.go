@@ -0,0 +1,118 @@
+package upstream
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of an upstream's circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed lets every request through
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects requests until the open timeout has passed
+	BreakerOpen
+	// BreakerHalfOpen lets a few probe requests through to decide whether to close
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// Breaker stops traffic to an upstream after consecutive failures
+type Breaker struct {
+	cfg Config
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	successes int
+	probes    int
+	openedAt  time.Time
+}
+
+func NewBreaker(cfg Config) *Breaker {
+	return &Breaker{cfg: cfg}
+}
+
+// State returns the breaker's current state
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Allow reports whether a request may be sent, returning ErrCircuitOpen if not.
+// Every allowed request must be followed by a call to Record.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cfg.BreakerOpenTimeout {
+			return ErrCircuitOpen
+		}
+		b.transition(BreakerHalfOpen)
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probes >= b.cfg.BreakerHalfOpenRequests {
+			return ErrCircuitOpen
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// Record reports the outcome of an allowed request. It returns the new state
+// and whether the outcome changed it.
+func (b *Breaker) Record(success bool) (BreakerState, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	previous := b.state
+	switch b.state {
+	case BreakerClosed:
+		if success {
+			b.failures = 0
+			break
+		}
+		b.failures++
+		if b.failures >= b.cfg.BreakerFailureThreshold {
+			b.transition(BreakerOpen)
+		}
+	case BreakerHalfOpen:
+		if !success {
+			b.transition(BreakerOpen)
+			break
+		}
+		b.successes++
+		if b.successes >= b.cfg.BreakerHalfOpenRequests {
+			b.transition(BreakerClosed)
+		}
+	}
+	return b.state, b.state != previous
+}
+
+func (b *Breaker) transition(state BreakerState) {
+	b.state = state
+	b.failures = 0
+	b.successes = 0
+	b.probes = 0
+	if state == BreakerOpen {
+		b.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,121 @@
+package upstream
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// breakerStep is one call on a breaker: "allow", "success", "failure", or
+// "expire" to let the open timeout pass
+type breakerStep struct {
+	op string
+	// blocked is whether allow should return ErrCircuitOpen
+	blocked bool
+	state   BreakerState
+}
+
+func TestBreaker(t *testing.T) {
+	cfg := Config{
+		BreakerFailureThreshold: 3,
+		BreakerOpenTimeout:      time.Minute,
+		BreakerHalfOpenRequests: 2,
+	}
+
+	tests := []struct {
+		name  string
+		steps []breakerStep
+	}{
+		{
+			name: "trips after consecutive failures",
+			steps: []breakerStep{
+				{op: "failure", state: BreakerClosed},
+				{op: "failure", state: BreakerClosed},
+				{op: "failure", state: BreakerOpen},
+				{op: "allow", blocked: true, state: BreakerOpen},
+			},
+		},
+		{
+			name: "a success resets the failure count",
+			steps: []breakerStep{
+				{op: "failure", state: BreakerClosed},
+				{op: "failure", state: BreakerClosed},
+				{op: "success", state: BreakerClosed},
+				{op: "failure", state: BreakerClosed},
+				{op: "failure", state: BreakerClosed},
+				{op: "allow", state: BreakerClosed},
+			},
+		},
+		{
+			name: "half opens once the open timeout has passed",
+			steps: []breakerStep{
+				{op: "failure"}, {op: "failure"}, {op: "failure", state: BreakerOpen},
+				{op: "allow", blocked: true, state: BreakerOpen},
+				{op: "expire", state: BreakerOpen},
+				{op: "allow", state: BreakerHalfOpen},
+			},
+		},
+		{
+			name: "half open lets a limited number of probes through",
+			steps: []breakerStep{
+				{op: "failure"}, {op: "failure"}, {op: "failure", state: BreakerOpen},
+				{op: "expire", state: BreakerOpen},
+				{op: "allow", state: BreakerHalfOpen},
+				{op: "allow", state: BreakerHalfOpen},
+				{op: "allow", blocked: true, state: BreakerHalfOpen},
+			},
+		},
+		{
+			name: "closes after enough successful probes",
+			steps: []breakerStep{
+				{op: "failure"}, {op: "failure"}, {op: "failure", state: BreakerOpen},
+				{op: "expire", state: BreakerOpen},
+				{op: "allow", state: BreakerHalfOpen},
+				{op: "success", state: BreakerHalfOpen},
+				{op: "allow", state: BreakerHalfOpen},
+				{op: "success", state: BreakerClosed},
+				{op: "allow", state: BreakerClosed},
+			},
+		},
+		{
+			name: "a failed probe opens it again",
+			steps: []breakerStep{
+				{op: "failure"}, {op: "failure"}, {op: "failure", state: BreakerOpen},
+				{op: "expire", state: BreakerOpen},
+				{op: "allow", state: BreakerHalfOpen},
+				{op: "success", state: BreakerHalfOpen},
+				{op: "allow", state: BreakerHalfOpen},
+				{op: "failure", state: BreakerOpen},
+				{op: "allow", blocked: true, state: BreakerOpen},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := NewBreaker(cfg)
+			for i, step := range tt.steps {
+				switch step.op {
+				case "allow":
+					err := breaker.Allow()
+					if blocked := errors.Is(err, ErrCircuitOpen); blocked != step.blocked {
+						t.Fatalf("step %d: Allow() = %v, want blocked %t", i, err, step.blocked)
+					}
+				case "success", "failure":
+					previous := breaker.State()
+					state, changed := breaker.Record(step.op == "success")
+					if changed != (state != previous) {
+						t.Fatalf("step %d: Record reported changed %t going from %v to %v", i, changed, previous, state)
+					}
+				case "expire":
+					breaker.mu.Lock()
+					breaker.openedAt = time.Now().Add(-cfg.BreakerOpenTimeout)
+					breaker.mu.Unlock()
+				}
+				if got := breaker.State(); got != step.state {
+					t.Fatalf("step %d (%s): state %v, want %v", i, step.op, got, step.state)
+				}
+			}
+		})
+	}
+}
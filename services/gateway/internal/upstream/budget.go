@@ -0,0 +1,37 @@
+package upstream
+
+import "sync"
+
+// retryBudget caps retries to a share of an upstream's traffic so retries
+// can't multiply the load on an upstream that is already struggling. Every
+// request earns a fraction of a token and every retry spends a whole one.
+type retryBudget struct {
+	mu     sync.Mutex
+	tokens float64
+	max    float64
+	ratio  float64
+}
+
+func newRetryBudget(percent, burst int) *retryBudget {
+	return &retryBudget{
+		tokens: float64(burst),
+		max:    float64(burst),
+		ratio:  float64(percent) / 100,
+	}
+}
+
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.max, b.tokens+b.ratio)
+}
+
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,48 @@
+package upstream
+
+import "testing"
+
+func TestRetryBudget(t *testing.T) {
+	tests := []struct {
+		name    string
+		percent int
+		burst   int
+		// deposits are made before withdrawing until the budget runs out
+		deposits int
+		want     int
+	}{
+		{name: "burst is available up front", percent: 20, burst: 3, want: 3},
+		{name: "burst caps what requests earn", percent: 20, burst: 0, deposits: 10, want: 0},
+		{name: "deposits stop at the burst", percent: 50, burst: 2, deposits: 100, want: 2},
+		{name: "no budget", percent: 0, burst: 0, deposits: 100, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := newRetryBudget(tt.percent, tt.burst)
+			for range tt.deposits {
+				budget.deposit()
+			}
+			got := 0
+			for budget.withdraw() {
+				got++
+				if got > tt.want {
+					break
+				}
+			}
+			if got != tt.want {
+				t.Fatalf("withdrew %d retries, want %d", got, tt.want)
+			}
+
+			// An empty budget refills from new requests
+			if tt.percent > 0 && tt.burst > 0 {
+				for range 100 / tt.percent {
+					budget.deposit()
+				}
+				if !budget.withdraw() {
+					t.Fatal("expected a retry after enough requests")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package upstream
+
+import (
+	"errors"
+	"fmt"
+	"gateway/internal/registry"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/proxy"
+	"go.uber.org/zap"
+)
+
+var errUpstreamStatus = errors.New("upstream responded with an error")
+
+// Config controls timeouts, retries and circuit breaking for proxied requests
+type Config struct {
+	// Timeout bounds each attempt unless the route sets its own
+	Timeout time.Duration
+	// MaxRetries is the most retries of one idempotent request
+	MaxRetries   int
+	RetryBackoff time.Duration
+	// RetryBudgetPercent of an upstream's requests may be retried, with up to
+	// RetryBudgetBurst retries available at once
+	RetryBudgetPercent int
+	RetryBudgetBurst   int
+	// Consecutive failures that open an upstream's breaker
+	BreakerFailureThreshold int
+	// How long an open breaker rejects requests before probing again
+	BreakerOpenTimeout time.Duration
+	// Probe requests let through, and successes needed, while half open
+	BreakerHalfOpenRequests int
+}
+
+// DefaultConfig returns the settings used when none are configured
+func DefaultConfig() Config {
+	return Config{
+		Timeout:                 10 * time.Second,
+		MaxRetries:              2,
+		RetryBackoff:            100 * time.Millisecond,
+		RetryBudgetPercent:      20,
+		RetryBudgetBurst:        10,
+		BreakerFailureThreshold: 5,
+		BreakerOpenTimeout:      30 * time.Second,
+		BreakerHalfOpenRequests: 1,
+	}
+}
+
+// Forwarder proxies requests to healthy instances of an upstream, guarding
+// each upstream with a circuit breaker and a retry budget
+type Forwarder struct {
+	cfg      Config
+	registry *registry.Registry
+	logger   *zap.Logger
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+	budgets  map[string]*retryBudget
+}
+
+func NewForwarder(cfg Config, registry *registry.Registry, logger *zap.Logger) *Forwarder {
+	defaults := DefaultConfig()
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.BreakerFailureThreshold <= 0 {
+		cfg.BreakerFailureThreshold = defaults.BreakerFailureThreshold
+	}
+	if cfg.BreakerOpenTimeout <= 0 {
+		cfg.BreakerOpenTimeout = defaults.BreakerOpenTimeout
+	}
+	if cfg.BreakerHalfOpenRequests <= 0 {
+		cfg.BreakerHalfOpenRequests = defaults.BreakerHalfOpenRequests
+	}
+	return &Forwarder{
+		cfg:      cfg,
+		registry: registry,
+		logger:   logger,
+		breakers: make(map[string]*Breaker),
+		budgets:  make(map[string]*retryBudget),
+	}
+}
+
+// Breaker returns the circuit breaker guarding an upstream
+func (f *Forwarder) Breaker(upstream string) *Breaker {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	breaker, ok := f.breakers[upstream]
+	if !ok {
+		breaker = NewBreaker(f.cfg)
+		f.breakers[upstream] = breaker
+	}
+	return breaker
+}
+
+func (f *Forwarder) budget(upstream string) *retryBudget {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	budget, ok := f.budgets[upstream]
+	if !ok {
+		budget = newRetryBudget(f.cfg.RetryBudgetPercent, f.cfg.RetryBudgetBurst)
+		f.budgets[upstream] = budget
+	}
+	return budget
+}
+
+// Forward proxies the request to path on an instance of upstream. Failures
+// are answered with a 503, or a 504 on timeout, naming the upstream.
+func (f *Forwarder) Forward(c *fiber.Ctx, upstream, path string) error {
+	return f.ForwardTimeout(c, upstream, path, f.cfg.Timeout)
+}
+
+// ForwardTimeout is Forward with an explicit timeout for each attempt
+func (f *Forwarder) ForwardTimeout(c *fiber.Ctx, upstream, path string, timeout time.Duration) error {
+	breaker := f.Breaker(upstream)
+	budget := f.budget(upstream)
+	budget.deposit()
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = f.attempt(c, upstream, path, breaker, timeout)
+		if err == nil {
+			c.Response().Header.Del(fiber.HeaderServer)
+			return nil
+		}
+		if !f.retryable(c, err, attempt) || !budget.withdraw() {
+			break
+		}
+		f.logger.Info("Retrying proxy request",
+			zap.String("request_id", c.Get(fiber.HeaderXRequestID)),
+			zap.String("upstream", upstream),
+			zap.String("path", path),
+			zap.Int("attempt", attempt+2),
+			zap.Error(err))
+		time.Sleep(time.Duration(attempt+1) * f.cfg.RetryBackoff)
+	}
+
+	// The upstream answered; pass its error response on as is
+	if errors.Is(err, errUpstreamStatus) {
+		c.Response().Header.Del(fiber.HeaderServer)
+		return nil
+	}
+
+	f.logger.Error("Proxy error",
+		zap.String("request_id", c.Get(fiber.HeaderXRequestID)),
+		zap.String("upstream", upstream),
+		zap.String("path", path),
+		zap.Error(err))
+
+	c.Response().Reset()
+	if isTimeout(err) {
+		return c.Status(fiber.StatusGatewayTimeout).JSON(fiber.Map{
+			"error":    "Upstream timed out",
+			"upstream": upstream,
+			"details":  err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+		"error":    "Upstream unavailable",
+		"upstream": upstream,
+		"details":  err.Error(),
+	})
+}
+
+func (f *Forwarder) attempt(c *fiber.Ctx, upstream, path string, breaker *Breaker, timeout time.Duration) error {
+	instance, err := f.registry.Next(upstream)
+	if err != nil {
+		return err
+	}
+	if err := breaker.Allow(); err != nil {
+		return err
+	}
+
+	span := startSpan(c, upstream, instance, path)
+	release := instance.Acquire()
+	err = proxy.DoTimeout(c, instance.URL+path, timeout)
+	release()
+	if err == nil && isUpstreamFailure(c.Response().StatusCode()) {
+		err = fmt.Errorf("%w: %s returned %d", errUpstreamStatus, instance.URL, c.Response().StatusCode())
+	}
+	endSpan(span, c, err)
+
+	if state, changed := breaker.Record(err == nil); changed {
+		f.logger.Warn("Circuit breaker changed state",
+			zap.String("upstream", upstream),
+			zap.String("state", state.String()))
+	}
+	return err
+}
+
+// retryable reports whether a failed attempt may be tried again. Only
+// idempotent requests are retried, and never once the upstream is known to
+// be unavailable.
+func (f *Forwarder) retryable(c *fiber.Ctx, err error, attempt int) bool {
+	if attempt >= f.cfg.MaxRetries || !isIdempotent(c.Method()) {
+		return false
+	}
+	return !errors.Is(err, ErrCircuitOpen) &&
+		!errors.Is(err, registry.ErrNoHealthyInstances) &&
+		!errors.Is(err, registry.ErrUnknownService)
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions,
+		fiber.MethodPut, fiber.MethodDelete, fiber.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// isUpstreamFailure reports statuses that mean the upstream, rather than the
+// request, is at fault
+func isUpstreamFailure(status int) bool {
+	return status == fiber.StatusBadGateway ||
+		status == fiber.StatusServiceUnavailable ||
+		status == fiber.StatusGatewayTimeout
+}
+
+func isTimeout(err error) bool {
+	var timeout interface{ Timeout() bool }
+	return errors.As(err, &timeout) && timeout.Timeout()
+}
@@ -0,0 +1,46 @@
+package upstream
+
+import (
+	"gateway/internal/registry"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("gateway/internal/upstream")
+
+// startSpan starts a client span for one attempt and passes its context on to
+// the upstream in the request headers
+func startSpan(c *fiber.Ctx, upstream string, instance *registry.Instance, path string) trace.Span {
+	ctx, span := tracer.Start(c.UserContext(), c.Method()+" "+upstream,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("peer.service", upstream),
+			attribute.String("http.request.method", c.Method()),
+			attribute.String("server.address", instance.URL),
+			attribute.String("url.path", path),
+		),
+	)
+
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	for key, value := range carrier {
+		c.Request().Header.Set(key, value)
+	}
+	return span
+}
+
+// endSpan records the upstream's answer, or err, and ends span
+func endSpan(span trace.Span, c *fiber.Ctx, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(attribute.Int("http.response.status_code", c.Response().StatusCode()))
+	}
+	span.End()
+}
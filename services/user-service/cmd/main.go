@@ -8,14 +8,23 @@ import (
 	"syscall"
 	"user-service/internal/config"
 	"user-service/internal/models"
+	"user-service/internal/pkg/storage"
 	"user-service/internal/rabbitmq"
 	"user-service/internal/repositories"
 	"user-service/internal/routes"
 	"user-service/internal/services"
 
+	"github.com/gofiber/contrib/fiberzap"
+	"github.com/gofiber/contrib/otelfiber/v2"
 	"github.com/gofiber/fiber/v2"
+	"github.com/whotterre/entritts/pkg/contracts"
 	"github.com/whotterre/entritts/pkg/database"
+	"github.com/whotterre/entritts/pkg/health"
+	"github.com/whotterre/entritts/pkg/inbox"
+	"github.com/whotterre/entritts/pkg/metrics"
 	rabbit "github.com/whotterre/entritts/pkg/rabbitmq"
+	"github.com/whotterre/entritts/pkg/requestid"
+	"github.com/whotterre/entritts/pkg/tracing"
 	"go.uber.org/zap"
 )
 
@@ -38,13 +47,38 @@ func main() {
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.Fatal("Failed to set up tracing", zap.Error(err))
+	}
+	defer shutdownTracing(context.Background())
+
+	app.Use(requestid.Middleware())
+	app.Use(otelfiber.Middleware())
+	app.Use(metrics.Middleware())
+	app.Get("/metrics", metrics.Handler())
+	app.Use(fiberzap.New(fiberzap.Config{
+		Logger: logger,
+		Fields: []string{"latency", "status", "method", "url", "requestId"},
+	}))
+
 	// Initialize the database
 	db, err := database.NewPostgresConnection(dbConfig)
 	if err != nil {
 		log.Println("Failed to create a new PostgreSQL database connection", err)
 	}
+	if err := tracing.InstrumentGORM(db); err != nil {
+		logger.Fatal("Failed to instrument database", zap.Error(err))
+	}
+	if err := metrics.RegisterDB(db, cfg.DBName); err != nil {
+		logger.Fatal("Failed to register database metrics", zap.Error(err))
+	}
 	// Auto-migrate database models
-	if err := db.AutoMigrate(&models.User{}, &models.UserRole{}, &models.UserSession{}); err != nil {
+	if err := db.AutoMigrate(&models.User{}, &models.UserRole{}, &models.UserSession{},
+		&models.UserMFA{}, &models.MFARecoveryCode{}, &models.MFAChallenge{}, &models.MFAPolicy{},
+		&models.UserIdentity{}, &models.OIDCLoginState{},
+		&models.OrganizerStats{}, &models.OrganizerEvent{}, &models.OrganizerSale{},
+		&inbox.ProcessedMessage{}); err != nil {
 		log.Printf("Failed to migrate database: %v", err)
 		return
 	}
@@ -53,7 +87,17 @@ func main() {
 	// Initialize repositories and services
 	userRepo := repositories.NewUserRepository(db)
 	sessionRepo := repositories.NewSessionRepository(db)
-	userService := services.NewUserService(userRepo, sessionRepo)
+	roleRepo := repositories.NewRoleRepository(db)
+	mfaRepo := repositories.NewMFARepository(db)
+	organizerRepo := repositories.NewOrganizerRepository(db)
+	userService := services.NewUserService(userRepo, sessionRepo, roleRepo, mfaRepo)
+	organizerService := services.NewOrganizerService(userRepo, organizerRepo)
+
+	if cfg.BootstrapAdminEmail != "" {
+		if err := userService.BootstrapAdmin(cfg.BootstrapAdminEmail, logger); err != nil {
+			logger.Error("Failed to bootstrap the admin", zap.Error(err))
+		}
+	}
 
 	// Setup RabbitMQ consumer
 	logger.Info("Initializing RabbitMQ consumer...")
@@ -68,7 +112,7 @@ func main() {
 
 	// Create consumer and event handler
 	consumer := rabbit.NewConsumer(rabbitClient)
-	eventConsumer := rabbitmq.NewUserEventConsumer(userService, logger)
+	eventConsumer := rabbitmq.NewUserEventConsumer(userService, organizerService, inbox.NewStore(db), logger)
 
 	// Setup queue and bindings
 	queueName := "user_events_queue"
@@ -98,19 +142,42 @@ func main() {
 		}
 	}
 
+	// Completed orders feed the organizer's sales figures
+	err = rabbitClient.EnsureExchange("tickets", "topic")
+	if err != nil {
+		logger.Error("Failed to declare exchange", zap.Error(err))
+		log.Fatal("Exchange declaration failed")
+	}
+	err = rabbitClient.BindQueue(queueName, "order.completed", "tickets")
+	if err != nil {
+		logger.Error("Failed to bind queue",
+			zap.String("routing_key", "order.completed"),
+			zap.Error(err))
+		log.Fatal("Queue binding failed")
+	}
+
 	// Start consuming events
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	consumeOpts := rabbit.ConsumeOptions{
-		QueueName:     queueName,
-		ConsumerTag:   "user-service-consumer",
-		AutoAck:       false, // Manual acknowledgment for reliability
-		Exclusive:     false,
-		NoLocal:       false,
-		NoWait:        false,
-		PrefetchCount: 10,
-		PrefetchSize:  0,
+		QueueName:      queueName,
+		ConsumerTag:    "user-service-consumer",
+		AutoAck:        false, // Manual acknowledgment for reliability
+		Exclusive:      false,
+		NoLocal:        false,
+		NoWait:         false,
+		PrefetchCount:  10,
+		PrefetchSize:   0,
+		Retry:          rabbit.DefaultRetryPolicy(),
+		Workers:        cfg.Consumer.Workers,
+		HandlerTimeout: cfg.Consumer.HandlerTimeout,
+		// Messages about the same event or order are handled in arrival order
+		OrderingKey: rabbitmq.OrderingKey,
+		Accept:      rabbitmq.AcceptedContentTypes,
+	}
+	if cfg.Consumer.ValidateMessages {
+		consumeOpts.Validate = contracts.ValidateDelivery
 	}
 
 	err = consumer.Consume(ctx, consumeOpts, eventConsumer.HandleEventMessage)
@@ -121,8 +188,28 @@ func main() {
 
 	logger.Info("RabbitMQ consumer started successfully")
 
+	// Initialize object storage for uploads
+	objectStore, err := newObjectStore(cfg.Storage)
+	if err != nil {
+		logger.Error("Failed to initialize object storage", zap.Error(err))
+		log.Fatal("Object storage initialization failed")
+	}
+
+	// Liveness only needs the process; readiness needs the database, the broker
+	// and the consumer. /health is what the gateway probes, so only the database
+	// takes an instance out of rotation: HTTP requests don't need the broker.
+	checker := health.NewChecker(cfg.ServiceName, cfg.HealthCheckTimeout)
+	checker.Add("database", func(ctx context.Context) error {
+		return database.HealthCheckContext(ctx, db)
+	})
+	checker.Add("rabbitmq", rabbitClient.HealthCheck)
+	checker.Add("consumer", consumer.HealthCheck)
+	app.Get("/health/live", checker.Live)
+	app.Get("/health/ready", checker.Ready)
+	app.Get("/health", checker.ReadyFor("database"))
+
 	// Setup routes
-	routes.SetupRoutes(app, db, cfg.PasetoSecret, logger)
+	routes.SetupRoutes(app, db, cfg, objectStore, logger)
 
 	// Setup graceful shutdown
 	c := make(chan os.Signal, 1)
@@ -131,6 +218,13 @@ func main() {
 	go func() {
 		<-c
 		logger.Info("Shutting down gracefully...")
+
+		// Let in-flight messages finish before the connection is closed
+		drainCtx, drainCancel := context.WithTimeout(context.Background(), cfg.Consumer.ShutdownTimeout)
+		defer drainCancel()
+		if err := consumer.Stop(drainCtx); err != nil {
+			logger.Warn("Consumer did not drain before the deadline", zap.Error(err))
+		}
 		cancel() // Cancel consumer context
 		app.Shutdown()
 	}()
@@ -141,3 +235,19 @@ func main() {
 		logger.Error("Server failed to start", zap.Error(err))
 	}
 }
+
+// newObjectStore picks the storage backend configured through STORAGE_BACKEND
+func newObjectStore(cfg config.StorageConfig) (storage.ObjectStore, error) {
+	if cfg.Backend == "s3" {
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:      cfg.S3Endpoint,
+			Region:        cfg.S3Region,
+			Bucket:        cfg.S3Bucket,
+			AccessKey:     cfg.S3AccessKey,
+			SecretKey:     cfg.S3SecretKey,
+			UseSSL:        cfg.S3UseSSL,
+			PublicBaseURL: cfg.S3PublicURL,
+		})
+	}
+	return storage.NewLocalStore(cfg.LocalDir, cfg.PublicURL)
+}
@@ -0,0 +1,25 @@
+package dto
+
+type TOTPEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type ConfirmTOTPDto struct {
+	Code string `json:"code" validate:"required,len=6"`
+}
+
+type ConfirmTOTPResponse struct {
+	RecoveryCodes []string           `json:"recovery_codes"`
+	Session       *LoginUserResponse `json:"session,omitempty"`
+}
+
+type VerifyMFADto struct {
+	MFAToken     string `json:"mfa_token" validate:"required"`
+	Code         string `json:"code" validate:"omitempty,len=6"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type SetMFAPolicyDto struct {
+	RequireMFA bool `json:"require_mfa"`
+}
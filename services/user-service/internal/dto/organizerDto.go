@@ -0,0 +1,37 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type OrganizerEventSummary struct {
+	EventID   uuid.UUID  `json:"event_id"`
+	Title     string     `json:"title"`
+	StartDate *time.Time `json:"start_date,omitempty"`
+}
+
+// OrganizerProfileResponse is the public view of an organizer
+type OrganizerProfileResponse struct {
+	OrganizerID    uuid.UUID               `json:"organizer_id"`
+	FirstName      string                  `json:"first_name"`
+	LastName       string                  `json:"last_name"`
+	ProfilePicURL  string                  `json:"profile_pic_url,omitempty"`
+	EventsHosted   int                     `json:"events_hosted"`
+	UpcomingEvents int64                   `json:"upcoming_events"`
+	MemberSince    time.Time               `json:"member_since"`
+	Upcoming       []OrganizerEventSummary `json:"upcoming"`
+}
+
+// OrganizerDashboardResponse is the organizer's private view including sales
+type OrganizerDashboardResponse struct {
+	OrganizerID    uuid.UUID               `json:"organizer_id"`
+	EventsHosted   int                     `json:"events_hosted"`
+	UpcomingEvents int64                   `json:"upcoming_events"`
+	TicketsSold    int                     `json:"tickets_sold"`
+	Revenue        decimal.Decimal         `json:"revenue"`
+	Upcoming       []OrganizerEventSummary `json:"upcoming"`
+	UpdatedAt      *time.Time              `json:"updated_at,omitempty"`
+}
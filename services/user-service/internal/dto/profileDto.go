@@ -0,0 +1,25 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UpdateProfileDto struct {
+	FirstName   *string `json:"first_name" validate:"omitempty,min=2,max=50"`
+	LastName    *string `json:"last_name" validate:"omitempty,min=2,max=50"`
+	PhoneNumber *string `json:"phone_number" validate:"omitempty,e164"`
+}
+
+type UserProfileResponse struct {
+	UserID        uuid.UUID `json:"user_id"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	Email         string    `json:"email"`
+	PhoneNumber   string    `json:"phone_number,omitempty"`
+	ProfilePicURL string    `json:"profile_pic_url,omitempty"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -23,4 +23,9 @@ type LoginUserResponse struct {
 	RefreshToken string    `json:"refresh_token"`
 	SessionID    string    `json:"session_id"`
 	ExpiresIn    time.Time `json:"expires_in"`
+
+	// Set instead of the session fields when a second factor is needed
+	MFARequired           bool   `json:"mfa_required,omitempty"`
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"`
+	MFAToken              string `json:"mfa_token,omitempty"`
 }
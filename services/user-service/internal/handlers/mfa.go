@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"user-service/internal/dto"
+	"user-service/internal/middleware"
+	"user-service/internal/pkg/utils"
+	"user-service/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type MFAHandler struct {
+	mfaService   services.MFAService
+	logger       *zap.Logger
+	pasetoSecret string
+}
+
+func NewMFAHandler(mfaService services.MFAService, logger *zap.Logger, pasetoSecret string) *MFAHandler {
+	return &MFAHandler{
+		mfaService:   mfaService,
+		logger:       logger,
+		pasetoSecret: pasetoSecret,
+	}
+}
+
+// EnrollTOTP handles POST /users/mfa/totp/enroll
+func (h *MFAHandler) EnrollTOTP(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+
+	response, err := h.mfaService.BeginTOTPEnrollment(userID, middleware.RequestLogger(c, h.logger))
+	if err != nil {
+		return h.mfaError(c, "Failed to start TOTP enrolment", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Scan the otpauth URI with an authenticator app and confirm with a code",
+		"data":    response,
+	})
+}
+
+// ConfirmTOTP handles POST /users/mfa/totp/confirm
+func (h *MFAHandler) ConfirmTOTP(c *fiber.Ctx) error {
+	logger := middleware.RequestLogger(c, h.logger)
+	var input dto.ConfirmTOTPDto
+	if err := c.BodyParser(&input); err != nil {
+		logger.Error("Failed to parse request body", zap.Error(err))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	userID := c.Locals("userID").(string)
+	// Users forced into enrolment at login get their session once they confirm
+	issueNewSession := c.Locals("tokenPurpose") == utils.PurposeMFAEnroll
+
+	response, err := h.mfaService.ConfirmTOTPEnrollment(userID, input, issueNewSession, logger, h.pasetoSecret)
+	if err != nil {
+		return h.mfaError(c, "Failed to confirm TOTP enrolment", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Multi-factor authentication enabled. Store the recovery codes somewhere safe",
+		"data":    response,
+	})
+}
+
+// VerifyLogin handles POST /users/login/mfa
+func (h *MFAHandler) VerifyLogin(c *fiber.Ctx) error {
+	logger := middleware.RequestLogger(c, h.logger)
+	var input dto.VerifyMFADto
+	if err := c.BodyParser(&input); err != nil {
+		logger.Error("Failed to parse request body", zap.Error(err))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	response, err := h.mfaService.VerifyLoginChallenge(input, logger, h.pasetoSecret)
+	if err != nil {
+		return h.mfaError(c, "Login failed", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "User logged in successfully",
+		"user": fiber.Map{
+			"accessToken":  response.AccessToken,
+			"expiresIn":    response.ExpiresIn,
+			"refreshToken": response.RefreshToken,
+			"sessionID":    response.SessionID,
+		},
+	})
+}
+
+// GetPolicies handles GET /users/admin/mfa-policies
+func (h *MFAHandler) GetPolicies(c *fiber.Ctx) error {
+	policies, err := h.mfaService.GetPolicies()
+	if err != nil {
+		return h.mfaError(c, "Failed to get MFA policies", err)
+	}
+	return c.JSON(fiber.Map{"data": policies})
+}
+
+// SetPolicy handles PUT /users/admin/mfa-policies/:role
+func (h *MFAHandler) SetPolicy(c *fiber.Ctx) error {
+	var input dto.SetMFAPolicyDto
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	policy, err := h.mfaService.SetPolicy(c.Params("role"), input)
+	if err != nil {
+		return h.mfaError(c, "Failed to update MFA policy", err)
+	}
+
+	middleware.RequestLogger(c, h.logger).Info("MFA policy updated",
+		zap.String("role", policy.Role),
+		zap.Bool("require_mfa", policy.RequireMFA),
+		zap.String("updated_by", c.Locals("userID").(string)),
+	)
+	return c.JSON(fiber.Map{
+		"message": "MFA policy updated",
+		"data":    policy,
+	})
+}
+
+func (h *MFAHandler) mfaError(c *fiber.Ctx, message string, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrInvalidMFACode), errors.Is(err, services.ErrInvalidMFAToken):
+		status = fiber.StatusUnauthorized
+	case errors.Is(err, services.ErrMFAAlreadyEnabled):
+		status = fiber.StatusConflict
+	case errors.Is(err, services.ErrMFANotEnrolled),
+		errors.Is(err, services.ErrInvalidRole),
+		errors.Is(err, services.ErrMissingMFAResponse):
+		status = fiber.StatusBadRequest
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status = fiber.StatusNotFound
+	}
+
+	if status == fiber.StatusInternalServerError {
+		middleware.RequestLogger(c, h.logger).Error(message, zap.Error(err))
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error":   message,
+		"details": err.Error(),
+	})
+}
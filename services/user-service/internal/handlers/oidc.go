@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+	"user-service/internal/middleware"
+	"user-service/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// oidcBindingCookie ties a login to the browser that started it
+const (
+	oidcBindingCookie = "oidc_login"
+	oidcBindingMaxAge = 10 * time.Minute
+)
+
+type OIDCHandler struct {
+	oidcService  services.OIDCService
+	logger       *zap.Logger
+	pasetoSecret string
+}
+
+func NewOIDCHandler(oidcService services.OIDCService, logger *zap.Logger, pasetoSecret string) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService:  oidcService,
+		logger:       logger,
+		pasetoSecret: pasetoSecret,
+	}
+}
+
+// Login handles GET /users/oidc/:provider/login
+func (h *OIDCHandler) Login(c *fiber.Ctx) error {
+	provider := c.Params("provider")
+
+	authURL, binding, err := h.oidcService.BeginLogin(c.UserContext(), provider)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownProvider) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Unknown identity provider",
+			})
+		}
+		middleware.RequestLogger(c, h.logger).Error("Failed to start OIDC login", zap.String("provider", provider), zap.Error(err))
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error":   "Identity provider unavailable",
+			"details": err.Error(),
+		})
+	}
+
+	// Lax still sends the cookie on the provider's top level redirect back
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcBindingCookie,
+		Value:    binding,
+		MaxAge:   int(oidcBindingMaxAge.Seconds()),
+		HTTPOnly: true,
+		Secure:   c.Protocol() == "https",
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// Callback handles GET /users/oidc/:provider/callback
+func (h *OIDCHandler) Callback(c *fiber.Ctx) error {
+	logger := middleware.RequestLogger(c, h.logger)
+	provider := c.Params("provider")
+	binding := c.Cookies(oidcBindingCookie)
+	c.ClearCookie(oidcBindingCookie)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		logger.Warn("Identity provider returned an error",
+			zap.String("provider", provider),
+			zap.String("error", providerErr),
+		)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   "Login failed",
+			"details": providerErr + ": " + c.Query("error_description"),
+		})
+	}
+
+	response, err := h.oidcService.CompleteLogin(c.UserContext(), provider, c.Query("state"), binding, c.Query("code"), logger, h.pasetoSecret)
+	if err != nil {
+		status := fiber.StatusUnauthorized
+		switch {
+		case errors.Is(err, services.ErrUnknownProvider):
+			status = fiber.StatusNotFound
+		case errors.Is(err, services.ErrOIDCEmailUnverified), errors.Is(err, services.ErrOIDCEmailMissing):
+			status = fiber.StatusForbidden
+		}
+		logger.Error("An error occurred while completing OIDC login", zap.String("provider", provider), zap.Error(err))
+		return c.Status(status).JSON(fiber.Map{
+			"error":   "Login failed",
+			"details": err.Error(),
+		})
+	}
+
+	if response.MFARequired || response.MFAEnrollmentRequired {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message":               "Additional verification required",
+			"mfaRequired":           response.MFARequired,
+			"mfaEnrollmentRequired": response.MFAEnrollmentRequired,
+			"mfaToken":              response.MFAToken,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "User logged in successfully",
+		"user": fiber.Map{
+			"accessToken":  response.AccessToken,
+			"expiresIn":    response.ExpiresIn,
+			"refreshToken": response.RefreshToken,
+			"sessionID":    response.SessionID,
+		},
+	})
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"errors"
+	"user-service/internal/middleware"
+	"user-service/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type OrganizerHandler struct {
+	organizerService services.OrganizerService
+	logger           *zap.Logger
+}
+
+func NewOrganizerHandler(organizerService services.OrganizerService, logger *zap.Logger) *OrganizerHandler {
+	return &OrganizerHandler{
+		organizerService: organizerService,
+		logger:           logger,
+	}
+}
+
+// GetPublicProfile handles GET /users/organizers/:id
+func (h *OrganizerHandler) GetPublicProfile(c *fiber.Ctx) error {
+	profile, err := h.organizerService.GetPublicProfile(c.Params("id"))
+	if err != nil {
+		return h.organizerError(c, "Failed to get organizer profile", err)
+	}
+	return c.JSON(fiber.Map{"data": profile})
+}
+
+// GetDashboard handles GET /users/me/organizer-dashboard
+func (h *OrganizerHandler) GetDashboard(c *fiber.Ctx) error {
+	dashboard, err := h.organizerService.GetDashboard(c.Locals("userID").(string))
+	if err != nil {
+		return h.organizerError(c, "Failed to get organizer dashboard", err)
+	}
+	return c.JSON(fiber.Map{"data": dashboard})
+}
+
+func (h *OrganizerHandler) organizerError(c *fiber.Ctx, message string, err error) error {
+	if errors.Is(err, services.ErrOrganizerNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   message,
+			"details": err.Error(),
+		})
+	}
+
+	middleware.RequestLogger(c, h.logger).Error(message, zap.Error(err))
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error":   message,
+		"details": err.Error(),
+	})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"user-service/internal/dto"
+	"user-service/internal/middleware"
+	"user-service/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type ProfileHandler struct {
+	profileService services.ProfileService
+	logger         *zap.Logger
+}
+
+func NewProfileHandler(profileService services.ProfileService, logger *zap.Logger) *ProfileHandler {
+	return &ProfileHandler{
+		profileService: profileService,
+		logger:         logger,
+	}
+}
+
+// GetProfile handles GET /users/me
+func (h *ProfileHandler) GetProfile(c *fiber.Ctx) error {
+	profile, err := h.profileService.GetProfile(c.Locals("userID").(string))
+	if err != nil {
+		return h.profileError(c, "Failed to get profile", err)
+	}
+	return c.JSON(fiber.Map{"data": profile})
+}
+
+// UpdateProfile handles PATCH /users/me
+func (h *ProfileHandler) UpdateProfile(c *fiber.Ctx) error {
+	logger := middleware.RequestLogger(c, h.logger)
+	var input dto.UpdateProfileDto
+	if err := c.BodyParser(&input); err != nil {
+		logger.Error("Failed to parse request body", zap.Error(err))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	profile, err := h.profileService.UpdateProfile(c.Locals("userID").(string), input, logger)
+	if err != nil {
+		return h.profileError(c, "Failed to update profile", err)
+	}
+	return c.JSON(fiber.Map{
+		"message": "Profile updated successfully",
+		"data":    profile,
+	})
+}
+
+// UploadProfilePicture handles PUT /users/me/profile-picture (multipart field "picture")
+func (h *ProfileHandler) UploadProfilePicture(c *fiber.Ctx) error {
+	fileHeader, err := c.FormFile("picture")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Missing picture file",
+			"details": err.Error(),
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return h.profileError(c, "Failed to read picture", err)
+	}
+	defer file.Close()
+
+	profile, err := h.profileService.UploadProfilePicture(c.UserContext(), c.Locals("userID").(string), file, fileHeader.Size, middleware.RequestLogger(c, h.logger))
+	if err != nil {
+		return h.profileError(c, "Failed to upload profile picture", err)
+	}
+	return c.JSON(fiber.Map{
+		"message": "Profile picture updated successfully",
+		"data":    profile,
+	})
+}
+
+// DeleteAccount handles DELETE /users/me
+func (h *ProfileHandler) DeleteAccount(c *fiber.Ctx) error {
+	if err := h.profileService.DeleteAccount(c.UserContext(), c.Locals("userID").(string), middleware.RequestLogger(c, h.logger)); err != nil {
+		return h.profileError(c, "Failed to delete account", err)
+	}
+	return c.JSON(fiber.Map{
+		"message": "Account deleted successfully",
+	})
+}
+
+func (h *ProfileHandler) profileError(c *fiber.Ctx, message string, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrPhoneNumberTaken):
+		status = fiber.StatusConflict
+	case errors.Is(err, services.ErrInvalidProfileUpdate),
+		errors.Is(err, services.ErrInvalidPhoneNumber),
+		errors.Is(err, services.ErrUnsupportedImageType):
+		status = fiber.StatusBadRequest
+	case errors.Is(err, services.ErrProfilePictureTooLarge):
+		status = fiber.StatusRequestEntityTooLarge
+	}
+
+	if status == fiber.StatusInternalServerError {
+		middleware.RequestLogger(c, h.logger).Error(message, zap.Error(err))
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error":   message,
+		"details": err.Error(),
+	})
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"user-service/internal/dto"
+	"user-service/internal/middleware"
 	"user-service/internal/services"
 
 	"github.com/gofiber/fiber/v2"
@@ -24,13 +25,14 @@ func NewUserHandler(userService services.UserService, logger *zap.Logger, jwtSec
 }
 
 func (h *UserHandler) CreateNewUser(c *fiber.Ctx) error {
+	logger := middleware.RequestLogger(c, h.logger)
 	var input dto.CreateUserDto
 	if err := c.BodyParser(&input); err != nil {
-		h.logger.Error("Error parsing body while trying to sign up", zap.Error(err))
+		logger.Error("Error parsing body while trying to sign up", zap.Error(err))
 	}
-	h.logger.Info("Received registration request")
+	logger.Info("Received registration request")
 	if err := c.BodyParser(&input); err != nil {
-		h.logger.Error("Failed to parse request body", zap.Error(err))
+		logger.Error("Failed to parse request body", zap.Error(err))
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   "Invalid request body",
 			"details": err.Error(),
@@ -38,7 +40,7 @@ func (h *UserHandler) CreateNewUser(c *fiber.Ctx) error {
 	}
 
 	// Log the parsed input
-	h.logger.Info("Parsed input",
+	logger.Info("Parsed input",
 		zap.String("firstName", input.FirstName),
 		zap.String("lastName", input.LastName),
 		zap.String("email", input.Email),
@@ -46,18 +48,24 @@ func (h *UserHandler) CreateNewUser(c *fiber.Ctx) error {
 	)
 
 	// Log the user object being created
-	h.logger.Info("Creating user",
+	logger.Info("Creating user",
 		zap.String("firstName", input.FirstName),
 		zap.String("lastName", input.LastName),
 		zap.String("email", input.Email),
 		zap.String("phoneNumber", input.PhoneNumber),
 	)
-	if err := h.userService.CreateNewUser(input, h.logger); err != nil {
+	if err := h.userService.CreateNewUser(input, logger); err != nil {
 		if err == services.ErrUserAlreadyExists {
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 				"error": "User already exists with that email",
 			})
 		}
+		if err == services.ErrInvalidPhoneNumber {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "Invalid phone number",
+				"details": err.Error(),
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "Failed to create user",
 			"details": err.Error(),
@@ -75,35 +83,49 @@ func (h *UserHandler) CreateNewUser(c *fiber.Ctx) error {
 }
 
 func (h *UserHandler) LoginUser(c *fiber.Ctx) error {
+	logger := middleware.RequestLogger(c, h.logger)
 	var input dto.LoginUserDto
 
 	if err := c.BodyParser(&input); err != nil {
-		h.logger.Error("Error parsing body while trying to login", zap.Error(err))
+		logger.Error("Error parsing body while trying to login", zap.Error(err))
 	}
-	h.logger.Info("Received log in request")
+	logger.Info("Received log in request")
 	if err := c.BodyParser(&input); err != nil {
-		h.logger.Error("Failed to parse request body", zap.Error(err))
+		logger.Error("Failed to parse request body", zap.Error(err))
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   "Invalid request body",
 			"details": err.Error(),
 		})
 	}
 
-	h.logger.Info("Parsed input",
+	logger.Info("Parsed input",
 		zap.String("email", input.Email),
 		zap.String("password", input.Password),
 	)
 
 	// Call the service
-	response, err := h.userService.LoginUser(input, h.logger, h.jwtSecret)
+	response, err := h.userService.LoginUser(input, logger, h.jwtSecret)
 	if err != nil {
-		h.logger.Error("An error occurred while logging in user", zap.Error(err))
+		logger.Error("An error occurred while logging in user", zap.Error(err))
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error":   "Login failed",
 			"details": err.Error(),
 		})
 	}
 
+	if response.MFARequired || response.MFAEnrollmentRequired {
+		message := "Verification code required"
+		if response.MFAEnrollmentRequired {
+			message = "Multi-factor authentication enrolment required"
+		}
+		return c.Status(http.StatusOK).JSON(fiber.Map{
+			"message":               message,
+			"mfaRequired":           response.MFARequired,
+			"mfaEnrollmentRequired": response.MFAEnrollmentRequired,
+			"mfaToken":              response.MFAToken,
+		})
+	}
+
 	return c.Status(http.StatusOK).JSON(fiber.Map{
 		"message": "User logged in successfully",
 		"user": fiber.Map{
@@ -115,10 +137,3 @@ func (h *UserHandler) LoginUser(c *fiber.Ctx) error {
 		},
 	})
 }
-
-func (h *UserHandler) GetHealthStatus(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{
-		"status":  "OK",
-		"service": "user-service",
-	})
-}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"slices"
+	"strings"
+	"user-service/internal/pkg/utils"
+	"user-service/internal/repositories"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RequireAuth validates the Bearer PASETO token and stores the user ID in c.Locals("userID").
+// Regular access tokens are always accepted, purpose restricted tokens only when listed.
+func RequireAuth(pasetoSecret string, logger *zap.Logger, allowedPurposes ...string) fiber.Handler {
+	purposes := append([]string{""}, allowedPurposes...)
+
+	return func(c *fiber.Ctx) error {
+		authHeader := c.Get("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			logger.Warn("Missing or invalid authorization header")
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Missing or invalid authorization header",
+			})
+		}
+
+		tokenString := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+		for _, purpose := range purposes {
+			userID, err := utils.VerifyPasetoToken(pasetoSecret, tokenString, purpose)
+			if err != nil {
+				continue
+			}
+			c.Locals("userID", userID)
+			c.Locals("tokenPurpose", purpose)
+			return c.Next()
+		}
+
+		logger.Warn("Token validation failed")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid or expired token",
+		})
+	}
+}
+
+// RequireRole only lets through users holding at least one of the given roles.
+// It must run after RequireAuth.
+func RequireRole(roleRepository repositories.RoleRepository, logger *zap.Logger, roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := uuid.Parse(c.Locals("userID").(string))
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid user ID in token",
+			})
+		}
+
+		userRoles, err := roleRepository.GetUserRoles(userID)
+		if err != nil {
+			logger.Error("Failed to load user roles", zap.Error(err))
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to load user roles",
+			})
+		}
+
+		for _, role := range roles {
+			if slices.Contains(userRoles, role) {
+				return c.Next()
+			}
+		}
+
+		logger.Warn("Insufficient role", zap.String("user_id", userID.String()))
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Insufficient permissions",
+		})
+	}
+}
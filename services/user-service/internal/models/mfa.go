@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserMFA - holds a user's TOTP secret and enrolment state
+type UserMFA struct {
+	UserID     uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+	User       User      `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	TOTPSecret string    `gorm:"type:varchar(64);not null" json:"-"`
+	Enabled    bool      `gorm:"default:false" json:"enabled"`
+	// LastTOTPStep is the time step of the last accepted code, so it can't be replayed
+	LastTOTPStep int64      `gorm:"not null;default:0" json:"-"`
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// MFARecoveryCode - one-time recovery code, only the SHA-256 hash is stored
+type MFARecoveryCode struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	User      User       `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	CodeHash  string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// MFAChallenge - a pending second login step. The challenge token carries its
+// ID so attempts can be counted and the challenge used only once.
+type MFAChallenge struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	User       User       `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Attempts   int        `gorm:"not null;default:0" json:"attempts"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	ConsumedAt *time.Time `json:"consumed_at,omitempty"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// MFAPolicy - admin controlled switch that makes MFA mandatory for a role
+type MFAPolicy struct {
+	Role       string    `gorm:"type:varchar(50);primaryKey" json:"role"`
+	RequireMFA bool      `gorm:"default:false" json:"require_mfa"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// OrganizerStats - running totals per organizer, maintained from broker messages
+type OrganizerStats struct {
+	OrganizerID  uuid.UUID       `gorm:"type:uuid;primaryKey" json:"organizer_id"`
+	EventsHosted int             `gorm:"not null;default:0" json:"events_hosted"`
+	TicketsSold  int             `gorm:"not null;default:0" json:"tickets_sold"`
+	Revenue      decimal.Decimal `gorm:"type:decimal(14,2);not null;default:0" json:"revenue"`
+	UpdatedAt    time.Time       `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// OrganizerEvent - events already counted for an organizer. Keyed by event ID so
+// a redelivered event.created/deleted message is only applied once. An event
+// deleted before it was created is a tombstone with no organizer.
+type OrganizerEvent struct {
+	EventID     uuid.UUID  `gorm:"type:uuid;primaryKey" json:"event_id"`
+	OrganizerID uuid.UUID  `gorm:"type:uuid;not null;index" json:"organizer_id"`
+	Title       string     `gorm:"type:varchar(255)" json:"title"`
+	StartDate   *time.Time `json:"start_date,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	DeletedAt   *time.Time `json:"-"`
+}
+
+// OrganizerSale - orders already counted towards tickets sold and revenue
+type OrganizerSale struct {
+	OrderID     uuid.UUID       `gorm:"type:uuid;primaryKey" json:"order_id"`
+	OrganizerID uuid.UUID       `gorm:"type:uuid;not null;index" json:"organizer_id"`
+	EventID     uuid.UUID       `gorm:"type:uuid;not null" json:"event_id"`
+	Quantity    int             `gorm:"not null" json:"quantity"`
+	Amount      decimal.Decimal `gorm:"type:decimal(14,2);not null" json:"amount"`
+	CreatedAt   time.Time       `gorm:"autoCreateTime" json:"created_at"`
+}
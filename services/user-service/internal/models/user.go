@@ -12,10 +12,12 @@ type User struct {
 	FirstName     string    `gorm:"type:varchar(255);not null" json:"first_name"`
 	LastName      string    `gorm:"type:varchar(255);not null" json:"last_name"`
 	Email         string    `gorm:"type:varchar(128);not null;uniqueIndex" json:"email"`
-	PhoneNumber   string    `gorm:"type:varchar(11);not null;uniqueIndex" json:"phone_number"`
+	PhoneNumber   *string   `gorm:"type:varchar(16);uniqueIndex" json:"phone_number"` // nil for social sign-ups
 	PasswordHash  string    `gorm:"type:varchar(255);not null" json:"-"`
 	ProfilePicURL string    `gorm:"type:varchar(255)" json:"profile_pic_url"`
+	ProfilePicKey string    `gorm:"type:varchar(255)" json:"-"` // object store key of an uploaded picture
 	EmailVerified bool      `gorm:"default:false" json:"email_verified"`
 	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt     time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	AnonymisedAt  *time.Time `json:"-"` // set once the account has been deleted
 }
@@ -0,0 +1,145 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrMissingIDToken = errors.New("token response did not contain an id_token")
+	ErrNonceMismatch  = errors.New("id_token nonce does not match the login request")
+)
+
+// ProviderConfig describes a single OpenID Connect provider
+type ProviderConfig struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Claims are the ID token claims we care about
+type Claims struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
+	Picture       string `json:"picture"`
+}
+
+// Client runs the authorization code flow with PKCE against one provider.
+// Discovery happens on first use so a provider being down doesn't stop the service booting.
+type Client struct {
+	cfg        ProviderConfig
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	verifier *gooidc.IDTokenVerifier
+	oauth2   *oauth2.Config
+}
+
+// NewClient creates a client for a provider. httpClient may be nil to use a client with a 10s timeout.
+func NewClient(cfg ProviderConfig, httpClient *http.Client) *Client {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{gooidc.ScopeOpenID, "email", "profile"}
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{cfg: cfg, httpClient: httpClient}
+}
+
+func (c *Client) Name() string {
+	return c.cfg.Name
+}
+
+// GenerateVerifier returns a new random PKCE code verifier
+func GenerateVerifier() string {
+	return oauth2.GenerateVerifier()
+}
+
+// AuthCodeURL returns the provider URL the user should be redirected to
+func (c *Client) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	oauthConfig, _, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oauthConfig.AuthCodeURL(state, gooidc.Nonce(nonce), oauth2.S256ChallengeOption(codeVerifier)), nil
+}
+
+// Exchange trades the authorization code for tokens and validates the ID token
+// (signature, issuer, audience, expiry and nonce)
+func (c *Client) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Claims, error) {
+	oauthConfig, verifier, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = c.clientContext(ctx)
+
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange failed: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, ErrMissingIDToken
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("id_token validation failed: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, ErrNonceMismatch
+	}
+
+	var claims Claims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+	claims.Subject = idToken.Subject
+	return &claims, nil
+}
+
+// discover fetches the provider metadata once and caches the result
+func (c *Client) discover(ctx context.Context) (*oauth2.Config, *gooidc.IDTokenVerifier, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.oauth2 != nil {
+		return c.oauth2, c.verifier, nil
+	}
+
+	// The provider keeps this context for fetching signing keys later on,
+	// so it must outlive the request that triggered discovery
+	provider, err := gooidc.NewProvider(c.clientContext(context.WithoutCancel(ctx)), c.cfg.IssuerURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("oidc discovery for %s failed: %w", c.cfg.Name, err)
+	}
+
+	c.oauth2 = &oauth2.Config{
+		ClientID:     c.cfg.ClientID,
+		ClientSecret: c.cfg.ClientSecret,
+		RedirectURL:  c.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       c.cfg.Scopes,
+	}
+	c.verifier = provider.Verifier(&gooidc.Config{ClientID: c.cfg.ClientID})
+	return c.oauth2, c.verifier, nil
+}
+
+func (c *Client) clientContext(ctx context.Context) context.Context {
+	return gooidc.ClientContext(ctx, c.httpClient)
+}
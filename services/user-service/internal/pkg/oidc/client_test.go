@@ -0,0 +1,109 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"user-service/internal/pkg/oidc"
+	"user-service/internal/pkg/oidc/oidctest"
+)
+
+func newTestClient(t *testing.T) (*oidc.Client, *oidctest.Provider) {
+	provider := oidctest.NewProvider(t, "entritts")
+	client := oidc.NewClient(oidc.ProviderConfig{
+		Name:         "stub",
+		IssuerURL:    provider.Issuer(),
+		ClientID:     "entritts",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/callback",
+	}, nil)
+	return client, provider
+}
+
+func TestExchange(t *testing.T) {
+	login := oidctest.Login{Subject: "user-1", Email: "ada@example.com", EmailVerified: true, Name: "Ada Lovelace"}
+
+	tests := []struct {
+		name string
+		// login overrides what the provider puts in the ID token
+		login oidctest.Login
+		// verifier, when set, is sent instead of the one the challenge was made from
+		verifier string
+		// nonce, when set, is expected instead of the one sent to the provider
+		nonce   string
+		wantErr string
+		is      error
+	}{
+		{name: "valid", login: login},
+		{name: "PKCE verifier mismatch", login: login, verifier: oidc.GenerateVerifier(), wantErr: "code exchange failed"},
+		{name: "nonce in token differs", login: oidctest.Login{Subject: "user-1", Nonce: "replayed"}, is: oidc.ErrNonceMismatch},
+		{name: "nonce expected differs", login: login, nonce: "other", is: oidc.ErrNonceMismatch},
+		{name: "expired ID token", login: oidctest.Login{Subject: "user-1", Expiry: -time.Hour}, wantErr: "id_token validation failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			client, provider := newTestClient(t)
+
+			verifier := oidc.GenerateVerifier()
+			authURL, err := client.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			code, state := provider.Authorize(t, authURL, tt.login)
+			if state != "state-1" {
+				t.Fatalf("state = %q, want state-1", state)
+			}
+
+			if tt.verifier != "" {
+				verifier = tt.verifier
+			}
+			nonce := "nonce-1"
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+
+			claims, err := client.Exchange(ctx, code, verifier, nonce)
+			switch {
+			case tt.is != nil:
+				if !errors.Is(err, tt.is) {
+					t.Fatalf("Exchange error = %v, want %v", err, tt.is)
+				}
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Exchange error = %v, want %q", err, tt.wantErr)
+				}
+			default:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if claims.Subject != "user-1" || claims.Email != "ada@example.com" || !claims.EmailVerified || claims.Name != "Ada Lovelace" {
+					t.Fatalf("claims = %+v", claims)
+				}
+			}
+		})
+	}
+}
+
+func TestExchangeRedeemsCodeOnce(t *testing.T) {
+	ctx := context.Background()
+	client, provider := newTestClient(t)
+
+	verifier := oidc.GenerateVerifier()
+	authURL, err := client.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := provider.Authorize(t, authURL, oidctest.Login{Subject: "user-1"})
+
+	if _, err := client.Exchange(ctx, code, verifier, "nonce-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Exchange(ctx, code, verifier, "nonce-1"); err == nil {
+		t.Fatal("second exchange of the same code succeeded")
+	}
+}
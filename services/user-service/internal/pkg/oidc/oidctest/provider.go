@@ -0,0 +1,215 @@
+// Package oidctest runs an in-memory OpenID Connect provider for tests. It
+// serves discovery, JWKS and token endpoints and signs ID tokens with a key
+// generated per provider.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+const keyID = "oidctest"
+
+// Login is what the user authenticates as at the provider
+type Login struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	// Nonce, when set, replaces the nonce from the authorization request
+	Nonce string
+	// Expiry is added to the current time for the ID token's exp claim; zero
+	// means an hour, negative values issue an expired token
+	Expiry time.Duration
+}
+
+type authorization struct {
+	login     Login
+	challenge string
+	nonce     string
+}
+
+// Provider is a stub identity provider served by an httptest.Server
+type Provider struct {
+	Server   *httptest.Server
+	ClientID string
+
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+// NewProvider starts a provider that issues ID tokens for clientID. It is shut
+// down when the test ends.
+func NewProvider(t testing.TB, clientID string) *Provider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Provider{ClientID: clientID, key: key, codes: make(map[string]authorization)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/keys", p.keys)
+	mux.HandleFunc("/token", p.token)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Server.Close)
+	return p
+}
+
+// Issuer is the provider's issuer URL
+func (p *Provider) Issuer() string {
+	return p.Server.URL
+}
+
+// Authorize plays the user signing in at authURL and returns the code and state
+// the provider would redirect back with
+func (p *Provider) Authorize(t testing.TB, authURL string, login Login) (code, state string) {
+	t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if method := query.Get("code_challenge_method"); method != "S256" {
+		t.Fatalf("authorization request uses code_challenge_method %q, want S256", method)
+	}
+
+	code = randomString(t)
+	p.mu.Lock()
+	p.codes[code] = authorization{
+		login:     login,
+		challenge: query.Get("code_challenge"),
+		nonce:     query.Get("nonce"),
+	}
+	p.mu.Unlock()
+	return code, query.Get("state")
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.Issuer(),
+		"authorization_endpoint":                p.Issuer() + "/authorize",
+		"token_endpoint":                        p.Issuer() + "/token",
+		"jwks_uri":                              p.Issuer() + "/keys",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *Provider) keys(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": keyID,
+			"n":   encode(p.key.N.Bytes()),
+			"e":   encode(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+// token redeems a code once, checking the PKCE verifier against the challenge
+// sent with the authorization request
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	p.mu.Lock()
+	auth, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || encode(verifier[:]) != auth.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken, err := p.sign(auth)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access-" + r.PostForm.Get("code"),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// sign builds an RS256 ID token for an authorization
+func (p *Provider) sign(auth authorization) (string, error) {
+	now := time.Now()
+	expiry := auth.login.Expiry
+	if expiry == 0 {
+		expiry = time.Hour
+	}
+	nonce := auth.nonce
+	if auth.login.Nonce != "" {
+		nonce = auth.login.Nonce
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":            p.Issuer(),
+		"aud":            p.ClientID,
+		"sub":            auth.login.Subject,
+		"email":          auth.login.Email,
+		"email_verified": auth.login.EmailVerified,
+		"name":           auth.login.Name,
+		"nonce":          nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(expiry).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + encode(signature), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func randomString(t testing.TB) string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	return encode(buf)
+}
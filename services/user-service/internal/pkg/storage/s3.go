@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config configures any S3 compatible backend (AWS S3, MinIO, R2, ...)
+type S3Config struct {
+	Endpoint  string // host[:port] without scheme
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+	// PublicBaseURL is prepended to keys to build object URLs, e.g. a CDN.
+	// Defaults to the path-style bucket URL on the endpoint.
+	PublicBaseURL string
+}
+
+type S3Store struct {
+	client        *minio.Client
+	bucket        string
+	publicBaseURL string
+}
+
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publicBaseURL := cfg.PublicBaseURL
+	if publicBaseURL == "" {
+		scheme := "http"
+		if cfg.UseSSL {
+			scheme = "https"
+		}
+		publicBaseURL = fmt.Sprintf("%s://%s/%s", scheme, cfg.Endpoint, cfg.Bucket)
+	}
+
+	return &S3Store{
+		client:        client,
+		bucket:        cfg.Bucket,
+		publicBaseURL: strings.TrimRight(publicBaseURL, "/"),
+	}, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) (string, error) {
+	if key == "" {
+		return "", ErrInvalidKey
+	}
+	_, err := s.client.PutObject(ctx, s.bucket, key, body, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return "", err
+	}
+	return s.publicBaseURL + "/" + key, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if key == "" {
+		return ErrInvalidKey
+	}
+	// S3 treats deleting a missing key as success
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
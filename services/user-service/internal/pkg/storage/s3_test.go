@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// s3Stub is a MinIO style endpoint keeping objects in memory
+type s3Stub struct {
+	mu       sync.Mutex
+	objects  map[string]string
+	types    map[string]string
+	requests []string
+	// fail, when set, rejects every request with this S3 error code
+	fail string
+}
+
+func newS3Stub(t *testing.T) (*s3Stub, *httptest.Server) {
+	stub := &s3Stub{objects: make(map[string]string), types: make(map[string]string)}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	return stub, server
+}
+
+func (s *s3Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	if s.fail != "" {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>`+s.fail+`</Code><Message>denied</Message></Error>`)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+			body = decodeChunked(body)
+		}
+		s.objects[r.URL.Path] = string(body)
+		s.types[r.URL.Path] = r.Header.Get("Content-Type")
+		w.Header().Set("ETag", `"d41d8cd98f00b204e9800998ecf8427e"`)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// decodeChunked strips the per chunk signatures minio-go adds when signing a
+// streamed upload over plain HTTP: <size hex>;chunk-signature=...\r\n<data>\r\n
+func decodeChunked(body []byte) []byte {
+	var data []byte
+	for {
+		header, rest, ok := bytes.Cut(body, []byte("\r\n"))
+		if !ok {
+			return data
+		}
+		sizeHex, _, _ := bytes.Cut(header, []byte(";"))
+		size, err := strconv.ParseInt(string(sizeHex), 16, 64)
+		if err != nil || size == 0 || int64(len(rest)) < size {
+			return data
+		}
+		data = append(data, rest[:size]...)
+		body = bytes.TrimPrefix(rest[size:], []byte("\r\n"))
+	}
+}
+
+func newTestS3Store(t *testing.T, server *httptest.Server, publicBaseURL string) *S3Store {
+	store, err := NewS3Store(S3Config{
+		Endpoint: strings.TrimPrefix(server.URL, "http://"),
+		// A region skips the bucket location lookup
+		Region:        "us-east-1",
+		Bucket:        "uploads",
+		AccessKey:     "access",
+		SecretKey:     "secret",
+		PublicBaseURL: publicBaseURL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestS3StorePut(t *testing.T) {
+	stub, server := newS3Stub(t)
+	store := newTestS3Store(t, server, "")
+
+	url, err := store.Put(context.Background(), "profile-pictures/u1/a.png", strings.NewReader("png data"), 8, "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := server.URL + "/uploads/profile-pictures/u1/a.png"; url != want {
+		t.Fatalf("expected URL %q, got %q", want, url)
+	}
+
+	path := "/uploads/profile-pictures/u1/a.png"
+	if got := stub.objects[path]; got != "png data" {
+		t.Fatalf("expected the object to be stored, got %q", got)
+	}
+	if got := stub.types[path]; got != "image/png" {
+		t.Fatalf("expected content type image/png, got %q", got)
+	}
+}
+
+func TestS3StorePutPublicBaseURL(t *testing.T) {
+	_, server := newS3Stub(t)
+	store := newTestS3Store(t, server, "https://cdn.example.com/")
+
+	url, err := store.Put(context.Background(), "a.png", strings.NewReader("x"), 1, "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://cdn.example.com/a.png"; url != want {
+		t.Fatalf("expected URL %q, got %q", want, url)
+	}
+}
+
+func TestS3StoreDelete(t *testing.T) {
+	stub, server := newS3Stub(t)
+	store := newTestS3Store(t, server, "")
+
+	if _, err := store.Put(context.Background(), "a.png", strings.NewReader("x"), 1, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(context.Background(), "a.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stub.objects["/uploads/a.png"]; ok {
+		t.Fatal("expected the object to be deleted")
+	}
+}
+
+func TestS3StoreRejectsEmptyKey(t *testing.T) {
+	stub, server := newS3Stub(t)
+	store := newTestS3Store(t, server, "")
+
+	if _, err := store.Put(context.Background(), "", strings.NewReader("x"), 1, "image/png"); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected ErrInvalidKey from Put, got %v", err)
+	}
+	if err := store.Delete(context.Background(), ""); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected ErrInvalidKey from Delete, got %v", err)
+	}
+	if len(stub.requests) != 0 {
+		t.Fatalf("expected no requests, got %v", stub.requests)
+	}
+}
+
+func TestS3StorePutError(t *testing.T) {
+	stub, server := newS3Stub(t)
+	stub.fail = "AccessDenied"
+	store := newTestS3Store(t, server, "")
+
+	_, err := store.Put(context.Background(), "a.png", strings.NewReader("x"), 1, "image/png")
+	if err == nil || !strings.Contains(err.Error(), "denied") {
+		t.Fatalf("expected the backend's error, got %v", err)
+	}
+}
@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrInvalidKey = errors.New("invalid object key")
+
+// ObjectStore stores user uploaded files such as profile pictures
+type ObjectStore interface {
+	// Put uploads an object and returns the URL it can be fetched from
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) (string, error)
+	// Delete removes an object. Deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
@@ -1,13 +1,43 @@
 package utils
 
 import (
+	"errors"
 	"time"
 
 	"github.com/o1egl/paseto"
 )
 
+// Token purposes for short lived tokens that must not be accepted as access tokens
+const (
+	PurposeMFAChallenge = "mfa_challenge"
+	PurposeMFAEnroll    = "mfa_enroll"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
 // Sign PASETO token
 func SignPasetoToken(secretKey string, userID string, email string, expiry time.Duration) (string, error) {
+	return signPasetoClaims(secretKey, userID, email, "", nil, "", expiry)
+}
+
+// SignAccessToken signs a PASETO access token carrying the user's roles, which
+// the gateway checks against each route's required roles
+func SignAccessToken(secretKey string, userID string, email string, roles []string, expiry time.Duration) (string, error) {
+	return signPasetoClaims(secretKey, userID, email, "", roles, "", expiry)
+}
+
+// SignPurposeToken signs a PASETO token restricted to a single purpose
+func SignPurposeToken(secretKey string, userID string, email string, purpose string, expiry time.Duration) (string, error) {
+	return signPasetoClaims(secretKey, userID, email, purpose, nil, "", expiry)
+}
+
+// SignChallengeToken is a purpose token naming the stored challenge it answers
+// in its jti claim
+func SignChallengeToken(secretKey string, userID string, email string, purpose string, challengeID string, expiry time.Duration) (string, error) {
+	return signPasetoClaims(secretKey, userID, email, purpose, nil, challengeID, expiry)
+}
+
+func signPasetoClaims(secretKey string, userID string, email string, purpose string, roles []string, tokenID string, expiry time.Duration) (string, error) {
 	now := time.Now()
 	json := map[string]interface{}{
 		"user_id": userID,
@@ -15,9 +45,65 @@ func SignPasetoToken(secretKey string, userID string, email string, expiry time.
 		"exp":     now.Add(expiry).Unix(),
 		"iat":     now.Unix(),
 	}
+	if purpose != "" {
+		json["purpose"] = purpose
+	}
+	if roles != nil {
+		json["roles"] = roles
+	}
+	if tokenID != "" {
+		json["jti"] = tokenID
+	}
 	token, err := paseto.NewV2().Encrypt([]byte(secretKey), json, nil)
 	if err != nil {
 		return "", err
 	}
 	return token, nil
 }
+
+// VerifyPasetoToken decrypts a token, checks its expiry and that it was issued
+// for the given purpose ("" for regular access tokens). It returns the user ID.
+func VerifyPasetoToken(secretKey string, token string, purpose string) (string, error) {
+	json, err := verifyPasetoClaims(secretKey, token, purpose)
+	if err != nil {
+		return "", err
+	}
+	return json["user_id"].(string), nil
+}
+
+// VerifyChallengeToken is VerifyPasetoToken for tokens signed with
+// SignChallengeToken. It also returns the challenge ID.
+func VerifyChallengeToken(secretKey string, token string, purpose string) (string, string, error) {
+	json, err := verifyPasetoClaims(secretKey, token, purpose)
+	if err != nil {
+		return "", "", err
+	}
+	challengeID, ok := json["jti"].(string)
+	if !ok || challengeID == "" {
+		return "", "", ErrInvalidToken
+	}
+	return json["user_id"].(string), challengeID, nil
+}
+
+func verifyPasetoClaims(secretKey string, token string, purpose string) (map[string]interface{}, error) {
+	var json map[string]interface{}
+	if err := paseto.NewV2().Decrypt(token, []byte(secretKey), &json, nil); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	exp, ok := json["exp"].(float64)
+	if !ok || int64(exp) < time.Now().Unix() {
+		return nil, ErrInvalidToken
+	}
+
+	tokenPurpose, _ := json["purpose"].(string)
+	if tokenPurpose != purpose {
+		return nil, ErrInvalidToken
+	}
+
+	userID, ok := json["user_id"].(string)
+	if !ok || userID == "" {
+		return nil, ErrInvalidToken
+	}
+	return json, nil
+}
@@ -3,164 +3,186 @@ package rabbitmq
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"time"
 	"user-service/internal/services"
 
 	"github.com/google/uuid"
 	"github.com/rabbitmq/amqp091-go"
+	"github.com/shopspring/decimal"
+	"github.com/whotterre/entritts/pkg/contracts"
+	"github.com/whotterre/entritts/pkg/inbox"
+	"github.com/whotterre/entritts/pkg/metrics"
 	"github.com/whotterre/entritts/pkg/rabbitmq"
+	"github.com/whotterre/entritts/pkg/requestid"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
+// inboxConsumerName identifies this consumer in the shared processed-message inbox
+const inboxConsumerName = "user-service-consumer"
+
 type UserEventCustomer struct {
-	userService services.UserService
-	logger      *zap.Logger
+	userService      services.UserService
+	organizerService services.OrganizerService
+	inbox            *inbox.Store
+	router           *contracts.Router
+	logger           *zap.Logger
 }
 
-// EventMessage represents the structure of event messages
-type EventMessage struct {
-	EventID     string    `json:"event_id"`
-	Action      string    `json:"action"`
-	OrganizerID string    `json:"organizer_id"`
-	EventTitle  string    `json:"event_title"`
-	Timestamp   time.Time `json:"timestamp"`
+// AcceptedContentTypes are the encodings the consumer decodes. Both are accepted
+// while producers move from JSON to protobuf.
+var AcceptedContentTypes = []string{rabbitmq.ContentTypeJSON, rabbitmq.ContentTypeProtobuf}
+
+// OrderingKey keys deliveries by the event or order they are about so the
+// consumer handles messages for the same event one at a time, in the order they
+// are delivered. A message that failed comes back from a retry queue after the
+// ones behind it, so e.g. event.deleted can be handled before event.created.
+func OrderingKey(delivery amqp091.Delivery) string {
+	var body struct {
+		Data struct {
+			EventID string `json:"event_id"`
+			OrderID string `json:"order_id"`
+		} `json:"data"`
+	}
+	data, err := contracts.ToJSON(rabbitmq.MediaType(delivery), delivery.Body)
+	if err != nil {
+		return ""
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return ""
+	}
+	if body.Data.OrderID != "" {
+		return body.Data.OrderID
+	}
+	return body.Data.EventID
 }
 
-func NewUserEventConsumer(userService services.UserService, logger *zap.Logger) *UserEventCustomer {
-	return &UserEventCustomer{
-		userService: userService,
-		logger:      logger,
+func NewUserEventConsumer(userService services.UserService, organizerService services.OrganizerService, inboxStore *inbox.Store, logger *zap.Logger) *UserEventCustomer {
+	c := &UserEventCustomer{
+		userService:      userService,
+		organizerService: organizerService,
+		inbox:            inboxStore,
+		router:           contracts.NewRouter(),
+		logger:           logger,
 	}
+
+	contracts.On(c.router, c.handleEventCreated)
+	contracts.On(c.router, c.handleEventUpdated)
+	contracts.On(c.router, c.handleEventDeleted)
+	contracts.On(c.router, c.handleOrderCompleted)
+	return c
 }
 
-// HandleEventMessage processes incoming event messages
+// HandleEventMessage processes incoming event and order messages. Unknown
+// message types and versions are rejected without requeueing.
 func (c *UserEventCustomer) HandleEventMessage(ctx context.Context, msg amqp091.Delivery) error {
 	startTime := time.Now()
 
-	// Log the incoming message
-	c.logger.Info("Processing event message",
-		zap.String("event_id", ""),
-		zap.String("action", ""),
-		zap.String("routing_key", msg.RoutingKey),
-	)
-
-	// Parse the message body
-	var eventMsg EventMessage
-	if err := json.Unmarshal(msg.Body, &eventMsg); err != nil {
-		c.logger.Error("Failed to unmarshal event message",
+	if err := c.router.Handle(ctx, msg); err != nil {
+		c.log(ctx).Error("Failed to process message",
+			zap.String("routing_key", msg.RoutingKey),
+			zap.String("message_id", msg.MessageId),
 			zap.Error(err),
-			zap.String("message_body", string(msg.Body)),
 		)
-		return msg.Nack(false, false) 
+		return err
 	}
 
-	// Update log with parsed event details
-	c.logger.Info("Processing event message",
-		zap.String("event_id", eventMsg.EventID),
-		zap.String("action", eventMsg.Action),
+	c.log(ctx).Info("Message processed successfully",
+		zap.Duration("processing_time", time.Since(startTime)),
 		zap.String("routing_key", msg.RoutingKey),
+		zap.String("message_id", msg.MessageId),
 	)
+	return nil
+}
 
-	// Route message based on action
-	switch eventMsg.Action {
-	case "created":
-		if err := c.handleEventCreated(ctx, eventMsg); err != nil {
-			c.logger.Error("Failed to process event created",
-				zap.String("event_id", eventMsg.EventID),
-				zap.Error(err),
-			)
-			return msg.Nack(false, true) // Requeue for retry
-		}
-	case "updated":
-		if err := c.handleEventUpdated(ctx, eventMsg); err != nil {
-			c.logger.Error("Failed to process event updated",
-				zap.String("event_id", eventMsg.EventID),
-				zap.Error(err),
-			)
-			return msg.Nack(false, true)
-		}
-	case "deleted":
-		if err := c.handleEventDeleted(ctx, eventMsg); err != nil {
-			c.logger.Error("Failed to process event deleted",
-				zap.String("event_id", eventMsg.EventID),
-				zap.Error(err),
-			)
-			return msg.Nack(false, true) 
-		}
-	default:
-		c.logger.Warn("Unknown event action",
-			zap.String("action", eventMsg.Action),
-			zap.String("event_id", eventMsg.EventID),
-		)
-		return msg.Ack(false) 
+// log tags the logger with the ID of the request that led to the message
+func (c *UserEventCustomer) log(ctx context.Context) *zap.Logger {
+	if id := requestid.FromContext(ctx); id != "" {
+		return c.logger.With(zap.String("request_id", id))
 	}
+	return c.logger
+}
 
-	// Acknowledge successful processing
-	processingTime := time.Since(startTime)
-	c.logger.Info("Message processed successfully",
-		zap.Duration("processing_time", processingTime),
-		zap.String("message_id", msg.MessageId),
-	)
-
-	return msg.Ack(false)
+// process applies a message's side effects in the same transaction as its inbox
+// record so a redelivered message is never applied twice
+func (c *UserEventCustomer) process(ctx context.Context, messageID string, fn func(organizers services.OrganizerService) error) error {
+	processed, err := c.inbox.Process(ctx, inboxConsumerName, messageID, func(tx *gorm.DB) error {
+		return fn(c.organizerService.WithTx(tx))
+	})
+	if err != nil {
+		return err
+	}
+	if !processed {
+		c.log(ctx).Info("Skipping already processed message",
+			zap.String("message_id", messageID),
+		)
+	}
+	return nil
 }
 
 // handleEventCreated processes event creation messages
-func (c *UserEventCustomer) handleEventCreated(ctx context.Context, msg EventMessage) error {
-	c.logger.Info("Received event created message",
+func (c *UserEventCustomer) handleEventCreated(ctx context.Context, env contracts.Envelope[contracts.EventCreatedV1], _ amqp091.Delivery) error {
+	msg := env.Data
+	c.log(ctx).Info("Received event created message",
 		zap.String("event_id", msg.EventID),
 		zap.String("organizer_id", msg.OrganizerID),
-		zap.String("event_title", msg.EventTitle),
+		zap.String("event_title", msg.Title),
 	)
 
-	// Validate organizer ID format
-	_, err := uuid.Parse(msg.OrganizerID)
+	// Malformed IDs will never succeed, so don't requeue them
+	eventID, err := uuid.Parse(msg.EventID)
 	if err != nil {
-		c.logger.Error("Invalid organizer ID format",
-			zap.String("organizer_id", msg.OrganizerID),
-			zap.Error(err),
-		)
-		return err
+		return fmt.Errorf("%w: invalid event ID %q", rabbitmq.ErrPermanentFailure, msg.EventID)
+	}
+	organizerID, err := uuid.Parse(msg.OrganizerID)
+	if err != nil {
+		return fmt.Errorf("%w: invalid organizer ID %q", rabbitmq.ErrPermanentFailure, msg.OrganizerID)
 	}
 
 	// Get user by ID
 	user, err := c.userService.GetUserByID(ctx, msg.OrganizerID)
 	if err != nil {
-		c.logger.Warn("Organizer not found, skipping event processing",
-			zap.String("organizer_id", msg.OrganizerID),
-			zap.String("event_id", msg.EventID),
-		)
-		return nil
+		return err
 	}
 
-	// Check if user is nil (additional safety check)
 	if user == nil {
-		c.logger.Warn("User is nil, skipping event processing",
+		c.log(ctx).Warn("Organizer not found, skipping event processing",
 			zap.String("organizer_id", msg.OrganizerID),
 			zap.String("event_id", msg.EventID),
 		)
 		return nil
 	}
 
-	// Increment user's event count
-	err = c.userService.IncrementUserEventCount(ctx, user.ID.String())
+	applied := false
+	err = c.process(ctx, env.ID, func(organizers services.OrganizerService) error {
+		var err error
+		applied, err = organizers.RecordEventCreated(ctx, organizerID, eventID, msg.Title, msg.StartDate)
+		return err
+	})
 	if err != nil {
-		c.logger.Error("Failed to increment user event count",
+		c.log(ctx).Error("Failed to record event for organizer",
 			zap.String("user_id", user.ID.String()),
 			zap.String("event_id", msg.EventID),
 			zap.Error(err),
 		)
 		return err
 	}
+	if !applied {
+		c.log(ctx).Info("Event already counted for organizer, skipping",
+			zap.String("event_id", msg.EventID),
+		)
+		return nil
+	}
 
 	if err := c.publishEventProcessedConfirmation(msg.EventID, "event_count_incremented"); err != nil {
-		c.logger.Warn("Failed to publish confirmation event",
+		c.log(ctx).Warn("Failed to publish confirmation event",
 			zap.String("event_id", msg.EventID),
 			zap.Error(err),
 		)
 	}
 
-	c.logger.Info("Successfully processed event created",
+	c.log(ctx).Info("Successfully processed event created",
 		zap.String("event_id", msg.EventID),
 		zap.String("user_id", user.ID.String()),
 	)
@@ -169,62 +191,119 @@ func (c *UserEventCustomer) handleEventCreated(ctx context.Context, msg EventMes
 }
 
 // handleEventUpdated processes event update messages
-func (c *UserEventCustomer) handleEventUpdated(ctx context.Context, msg EventMessage) error {
-	c.logger.Info("Processing event updated",
+func (c *UserEventCustomer) handleEventUpdated(ctx context.Context, env contracts.Envelope[contracts.EventUpdatedV1], _ amqp091.Delivery) error {
+	msg := env.Data
+	c.log(ctx).Info("Processing event updated",
 		zap.String("event_id", msg.EventID),
-		zap.String("organizer_id", msg.OrganizerID),
 	)
 
-	// For now, just log the update - you can add specific business logic here
-	c.logger.Info("Event updated processed successfully",
+	eventID, err := uuid.Parse(msg.EventID)
+	if err != nil {
+		return fmt.Errorf("%w: invalid event ID %q", rabbitmq.ErrPermanentFailure, msg.EventID)
+	}
+
+	// Only a change of start date affects the organizer's upcoming events
+	raw, ok := msg.Updates["start_date"].(string)
+	if !ok {
+		return nil
+	}
+	startDate, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		c.log(ctx).Warn("Invalid start date in event update",
+			zap.String("event_id", msg.EventID),
+			zap.String("start_date", raw),
+		)
+		return nil
+	}
+
+	err = c.process(ctx, env.ID, func(organizers services.OrganizerService) error {
+		return organizers.RecordEventRescheduled(ctx, eventID, startDate)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.log(ctx).Info("Event updated processed successfully",
 		zap.String("event_id", msg.EventID),
 	)
 
 	return nil
 }
 
-// handleEventDeleted processes event deletion messages
-func (c *UserEventCustomer) handleEventDeleted(ctx context.Context, msg EventMessage) error {
-	c.logger.Info("Processing event deleted",
+// handleEventDeleted processes event deletion messages. These only carry the
+// event ID, the organizer is looked up from the events already counted.
+func (c *UserEventCustomer) handleEventDeleted(ctx context.Context, env contracts.Envelope[contracts.EventDeletedV1], _ amqp091.Delivery) error {
+	msg := env.Data
+	c.log(ctx).Info("Processing event deleted",
 		zap.String("event_id", msg.EventID),
-		zap.String("organizer_id", msg.OrganizerID),
 	)
 
-	// Validate organizer ID format
-	_, err := uuid.Parse(msg.OrganizerID)
+	eventID, err := uuid.Parse(msg.EventID)
+	if err != nil {
+		return fmt.Errorf("%w: invalid event ID %q", rabbitmq.ErrPermanentFailure, msg.EventID)
+	}
+
+	applied := false
+	err = c.process(ctx, env.ID, func(organizers services.OrganizerService) error {
+		var err error
+		applied, err = organizers.RecordEventDeleted(ctx, eventID)
+		return err
+	})
 	if err != nil {
-		c.logger.Error("Invalid organizer ID format",
-			zap.String("organizer_id", msg.OrganizerID),
-			zap.Error(err),
-		)
 		return err
 	}
 
-	// Get user by ID
-	user, err := c.userService.GetUserByID(ctx, msg.OrganizerID)
+	c.log(ctx).Info("Event deleted processed successfully",
+		zap.String("event_id", msg.EventID),
+		zap.Bool("stats_updated", applied),
+	)
+
+	return nil
+}
+
+// handleOrderCompleted adds a completed order to the organizer's sales figures
+func (c *UserEventCustomer) handleOrderCompleted(ctx context.Context, env contracts.Envelope[contracts.OrderCompletedV1], _ amqp091.Delivery) error {
+	msg := env.Data
+
+	orderID, err := uuid.Parse(msg.OrderID)
 	if err != nil {
-		c.logger.Warn("Organizer not found, skipping event processing",
-			zap.String("organizer_id", msg.OrganizerID),
-			zap.String("event_id", msg.EventID),
-		)
-		return nil
+		return fmt.Errorf("%w: invalid order ID %q", rabbitmq.ErrPermanentFailure, msg.OrderID)
+	}
+	eventID, err := uuid.Parse(msg.EventID)
+	if err != nil {
+		return fmt.Errorf("%w: invalid event ID %q", rabbitmq.ErrPermanentFailure, msg.EventID)
+	}
+	organizerID, err := uuid.Parse(msg.OrganizerID)
+	if err != nil {
+		return fmt.Errorf("%w: invalid organizer ID %q", rabbitmq.ErrPermanentFailure, msg.OrganizerID)
+	}
+	amount, err := decimal.NewFromString(msg.TotalAmount)
+	if err != nil {
+		return fmt.Errorf("%w: invalid total amount %q", rabbitmq.ErrPermanentFailure, msg.TotalAmount)
 	}
 
-	// Check if user is nil (additional safety check)
-	if user == nil {
-		c.logger.Warn("User is nil, skipping event processing",
-			zap.String("organizer_id", msg.OrganizerID),
-			zap.String("event_id", msg.EventID),
+	applied := false
+	err = c.process(ctx, env.ID, func(organizers services.OrganizerService) error {
+		var err error
+		applied, err = organizers.RecordTicketSale(ctx, orderID, organizerID, eventID, msg.Quantity, amount)
+		return err
+	})
+	if err != nil {
+		c.log(ctx).Error("Failed to record ticket sale",
+			zap.String("order_id", msg.OrderID),
+			zap.Error(err),
 		)
-		return nil
+		return err
+	}
+	if applied {
+		metrics.TicketsSold.Add(float64(msg.Quantity))
 	}
 
-	// You could decrement event count here if needed
-	c.logger.Info("Event deleted processed successfully",
-		zap.String("event_id", msg.EventID),
-		zap.String("user_id", user.ID.String()),
+	c.log(ctx).Info("Order processed successfully",
+		zap.String("order_id", msg.OrderID),
+		zap.String("message_id", env.ID),
+		zap.Bool("stats_updated", applied),
 	)
-
 	return nil
 }
 
@@ -247,12 +326,15 @@ func (c *UserEventCustomer) ConsumeEvents(ctx context.Context, consumer *rabbitm
 	consumeOpts := rabbitmq.ConsumeOptions{
 		QueueName:     queueName,
 		ConsumerTag:   "user-service-consumer",
-		AutoAck:       false, 
+		AutoAck:       false,
 		Exclusive:     false,
 		NoLocal:       false,
 		NoWait:        false,
 		PrefetchCount: 10,
 		PrefetchSize:  0,
+		Retry:         rabbitmq.DefaultRetryPolicy(),
+		OrderingKey:   OrderingKey,
+		Accept:        AcceptedContentTypes,
 	}
 
 	return consumer.Consume(ctx, consumeOpts, c.HandleEventMessage)
@@ -0,0 +1,170 @@
+package repositories
+
+import (
+	"time"
+	"user-service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MFARepository interface {
+	GetUserMFA(userID uuid.UUID) (*models.UserMFA, error)
+	SaveUserMFA(mfa *models.UserMFA) error
+	EnableUserMFA(userID uuid.UUID, codeHashes []string) error
+	UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error)
+	UseTOTPStep(userID uuid.UUID, step int64) (bool, error)
+	CreateChallenge(userID uuid.UUID, expiry time.Duration) (*models.MFAChallenge, error)
+	TakeChallengeAttempt(challengeID, userID uuid.UUID, maxAttempts int) (bool, error)
+	ConsumeChallenge(challengeID uuid.UUID) (bool, error)
+	GetPolicies() ([]models.MFAPolicy, error)
+	GetPoliciesForRoles(roles []string) ([]models.MFAPolicy, error)
+	SavePolicy(policy *models.MFAPolicy) error
+}
+
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) MFARepository {
+	return &mfaRepository{db: db}
+}
+
+func (r *mfaRepository) GetUserMFA(userID uuid.UUID) (*models.UserMFA, error) {
+	var mfa models.UserMFA
+	if err := r.db.Where("user_id = ?", userID).First(&mfa).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+// SaveUserMFA upserts the pending TOTP secret for a user
+func (r *mfaRepository) SaveUserMFA(mfa *models.UserMFA) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"totp_secret", "enabled", "confirmed_at", "updated_at"}),
+	}).Create(mfa).Error
+}
+
+// EnableUserMFA marks enrolment as confirmed and replaces any previous recovery codes
+func (r *mfaRepository) EnableUserMFA(userID uuid.UUID, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&models.UserMFA{}).
+			Where("user_id = ?", userID).
+			Updates(map[string]interface{}{
+				"enabled":      true,
+				"confirmed_at": &now,
+			}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		codes := make([]models.MFARecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, models.MFARecoveryCode{UserID: userID, CodeHash: hash})
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseRecoveryCode burns an unused recovery code. It reports false if no such code exists
+func (r *mfaRepository) UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error) {
+	result := r.db.Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// UseTOTPStep records step as the user's last accepted TOTP code. It reports
+// false if that step or a later one was already used.
+func (r *mfaRepository) UseTOTPStep(userID uuid.UUID, step int64) (bool, error) {
+	result := r.db.Model(&models.UserMFA{}).
+		Where("user_id = ? AND last_totp_step < ?", userID, step).
+		Update("last_totp_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CreateChallenge stores a new login challenge, dropping the user's expired ones
+func (r *mfaRepository) CreateChallenge(userID uuid.UUID, expiry time.Duration) (*models.MFAChallenge, error) {
+	now := time.Now()
+	challenge := &models.MFAChallenge{
+		ID:        uuid.New(),
+		UserID:    userID,
+		ExpiresAt: now.Add(expiry),
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND expires_at < ?", userID, now).Delete(&models.MFAChallenge{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(challenge).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// TakeChallengeAttempt counts an attempt at answering a challenge. It reports
+// false once the challenge is used, expired or out of attempts.
+func (r *mfaRepository) TakeChallengeAttempt(challengeID, userID uuid.UUID, maxAttempts int) (bool, error) {
+	result := r.db.Model(&models.MFAChallenge{}).
+		Where("id = ? AND user_id = ? AND attempts < ? AND consumed_at IS NULL AND expires_at > ?",
+			challengeID, userID, maxAttempts, time.Now()).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ConsumeChallenge marks a challenge as answered. It reports false if it
+// already was.
+func (r *mfaRepository) ConsumeChallenge(challengeID uuid.UUID) (bool, error) {
+	result := r.db.Model(&models.MFAChallenge{}).
+		Where("id = ? AND consumed_at IS NULL", challengeID).
+		Update("consumed_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *mfaRepository) GetPolicies() ([]models.MFAPolicy, error) {
+	var policies []models.MFAPolicy
+	if err := r.db.Order("role ASC").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+func (r *mfaRepository) GetPoliciesForRoles(roles []string) ([]models.MFAPolicy, error) {
+	var policies []models.MFAPolicy
+	if len(roles) == 0 {
+		return policies, nil
+	}
+	if err := r.db.Where("role IN ?", roles).Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+func (r *mfaRepository) SavePolicy(policy *models.MFAPolicy) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "role"}},
+		DoUpdates: clause.AssignmentColumns([]string{"require_mfa", "updated_at"}),
+	}).Create(policy).Error
+}
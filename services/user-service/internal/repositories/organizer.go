@@ -0,0 +1,166 @@
+package repositories
+
+import (
+	"time"
+	"user-service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OrganizerRepository interface {
+	RecordEventCreated(event *models.OrganizerEvent) (bool, error)
+	RecordEventDeleted(eventID uuid.UUID) (bool, error)
+	UpdateEventStartDate(eventID uuid.UUID, startDate time.Time) error
+	RecordSale(sale *models.OrganizerSale) (bool, error)
+	GetStats(organizerID uuid.UUID) (*models.OrganizerStats, error)
+	GetUpcomingEvents(organizerID uuid.UUID, limit int) ([]models.OrganizerEvent, error)
+	CountUpcomingEvents(organizerID uuid.UUID) (int64, error)
+	WithTx(tx *gorm.DB) OrganizerRepository
+}
+
+type organizerRepository struct {
+	db *gorm.DB
+}
+
+func NewOrganizerRepository(db *gorm.DB) OrganizerRepository {
+	return &organizerRepository{db: db}
+}
+
+// WithTx returns a repository whose writes join the given transaction
+func (r *organizerRepository) WithTx(tx *gorm.DB) OrganizerRepository {
+	return &organizerRepository{db: tx}
+}
+
+// RecordEventCreated counts a new event for its organizer and grants the
+// organizer role. It reports false when the event was already counted or
+// deleted.
+func (r *organizerRepository) RecordEventCreated(event *models.OrganizerEvent) (bool, error) {
+	applied := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := incrementStats(tx, event.OrganizerID, map[string]interface{}{
+			"events_hosted": gorm.Expr("organizer_stats.events_hosted + 1"),
+		}, &models.OrganizerStats{OrganizerID: event.OrganizerID, EventsHosted: 1}); err != nil {
+			return err
+		}
+
+		role := &models.UserRole{UserID: event.OrganizerID, Role: models.RoleOrganizer}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(role).Error; err != nil {
+			return err
+		}
+		applied = true
+		return nil
+	})
+	return applied, err
+}
+
+// RecordEventDeleted removes a deleted event from its organizer's totals. It
+// reports false when the event is unknown or was already removed. An unknown
+// event gets a tombstone, since its event.created may still arrive from a
+// retry queue and must not count it then.
+func (r *organizerRepository) RecordEventDeleted(eventID uuid.UUID) (bool, error) {
+	applied := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		var event models.OrganizerEvent
+		result := tx.Model(&event).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "organizer_id"}}}).
+			Where("event_id = ? AND deleted_at IS NULL", eventID).
+			Update("deleted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// The message doesn't name the organizer, so the tombstone has none
+			tombstone := &models.OrganizerEvent{EventID: eventID, DeletedAt: &now}
+			return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(tombstone).Error
+		}
+
+		if err := tx.Model(&models.OrganizerStats{}).
+			Where("organizer_id = ? AND events_hosted > 0", event.OrganizerID).
+			Update("events_hosted", gorm.Expr("events_hosted - 1")).Error; err != nil {
+			return err
+		}
+		applied = true
+		return nil
+	})
+	return applied, err
+}
+
+func (r *organizerRepository) UpdateEventStartDate(eventID uuid.UUID, startDate time.Time) error {
+	return r.db.Model(&models.OrganizerEvent{}).
+		Where("event_id = ?", eventID).
+		Update("start_date", startDate).Error
+}
+
+// RecordSale adds a completed order to tickets sold and revenue. It reports
+// false when the order was already counted.
+func (r *organizerRepository) RecordSale(sale *models.OrganizerSale) (bool, error) {
+	applied := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(sale)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := incrementStats(tx, sale.OrganizerID, map[string]interface{}{
+			"tickets_sold": gorm.Expr("organizer_stats.tickets_sold + ?", sale.Quantity),
+			"revenue":      gorm.Expr("organizer_stats.revenue + ?", sale.Amount),
+		}, &models.OrganizerStats{OrganizerID: sale.OrganizerID, TicketsSold: sale.Quantity, Revenue: sale.Amount}); err != nil {
+			return err
+		}
+		applied = true
+		return nil
+	})
+	return applied, err
+}
+
+func (r *organizerRepository) GetStats(organizerID uuid.UUID) (*models.OrganizerStats, error) {
+	var stats models.OrganizerStats
+	if err := r.db.Where("organizer_id = ?", organizerID).First(&stats).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func (r *organizerRepository) GetUpcomingEvents(organizerID uuid.UUID, limit int) ([]models.OrganizerEvent, error) {
+	var events []models.OrganizerEvent
+	err := r.upcoming(organizerID).Order("start_date ASC").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+func (r *organizerRepository) CountUpcomingEvents(organizerID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.upcoming(organizerID).Count(&count).Error
+	return count, err
+}
+
+// upcoming is derived at read time since it changes with the clock, not with messages
+func (r *organizerRepository) upcoming(organizerID uuid.UUID) *gorm.DB {
+	return r.db.Model(&models.OrganizerEvent{}).
+		Where("organizer_id = ? AND deleted_at IS NULL AND start_date > ?", organizerID, time.Now())
+}
+
+// incrementStats upserts the organizer's stats row, applying updates when it already exists
+func incrementStats(tx *gorm.DB, organizerID uuid.UUID, updates map[string]interface{}, initial *models.OrganizerStats) error {
+	updates["updated_at"] = time.Now()
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "organizer_id"}},
+		DoUpdates: clause.Assignments(updates),
+	}).Create(initial).Error
+}
@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"user-service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RoleRepository interface {
+	GetUserRoles(userID uuid.UUID) ([]string, error)
+	AssignRole(userID uuid.UUID, role string) error
+	RoleAssigned(role string) (bool, error)
+}
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+func (r *roleRepository) GetUserRoles(userID uuid.UUID) ([]string, error) {
+	var roles []string
+	if err := r.db.Model(&models.UserRole{}).Where("user_id = ?", userID).Pluck("role", &roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *roleRepository) AssignRole(userID uuid.UUID, role string) error {
+	userRole := &models.UserRole{UserID: userID, Role: role}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(userRole).Error
+}
+
+// RoleAssigned reports whether any user holds role
+func (r *roleRepository) RoleAssigned(role string) (bool, error) {
+	var count int64
+	if err := r.db.Model(&models.UserRole{}).Where("role = ?", role).Limit(1).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"log"
+	"time"
 	"user-service/internal/models"
 
 	"github.com/google/uuid"
@@ -12,8 +13,10 @@ type UserRepository interface {
 	CreateUser(user *models.User) error
 	GetUserByEmail(email string) (*models.User, error)
 	GetUserById(id uuid.UUID) (*models.User, error)
-	// GetAll()
-	// Delete(id string)
+	GetUserByPhoneNumber(phoneNumber string) (*models.User, error)
+	UpdateUser(id uuid.UUID, updates map[string]interface{}) (*models.User, error)
+	AnonymiseUser(id uuid.UUID) error
+	DeleteUser(id uuid.UUID) error
 }
 
 type userRepository struct {
@@ -52,6 +55,62 @@ func (r *userRepository) GetUserById(id uuid.UUID) (*models.User, error) {
 	return user, nil
 }
 
+func (r *userRepository) GetUserByPhoneNumber(phoneNumber string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where("phone_number = ?", phoneNumber).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) UpdateUser(id uuid.UUID, updates map[string]interface{}) (*models.User, error) {
+	if err := r.db.Model(&models.User{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return r.GetUserById(id)
+}
+
+// AnonymiseUser scrubs personal data from a user while keeping the row, so that
+// events and orders referencing the user ID stay consistent. Credentials,
+// sessions and linked identities are removed in the same transaction.
+func (r *userRepository) AnonymiseUser(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"first_name":      "Deleted",
+			"last_name":       "User",
+			"email":           "deleted-" + id.String() + "@anonymised.invalid",
+			"phone_number":    nil,
+			"password_hash":   "",
+			"profile_pic_url": "",
+			"profile_pic_key": "",
+			"email_verified":  false,
+			"anonymised_at":   &now,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		related := []interface{}{
+			&models.UserSession{},
+			&models.UserIdentity{},
+			&models.UserMFA{},
+			&models.MFARecoveryCode{},
+			&models.MFAChallenge{},
+			&models.UserRole{},
+		}
+		for _, model := range related {
+			if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (r *userRepository) DeleteUser(id uuid.UUID) error {
 	var user *models.User
 	if err := r.db.Where("id = ?", id).Delete(&user).Error; err != nil {
@@ -1,7 +1,13 @@
 package routes
 
 import (
+	"user-service/internal/config"
 	"user-service/internal/handlers"
+	"user-service/internal/middleware"
+	"user-service/internal/models"
+	"user-service/internal/pkg/oidc"
+	"user-service/internal/pkg/storage"
+	"user-service/internal/pkg/utils"
 	"user-service/internal/repositories"
 	"user-service/internal/services"
 
@@ -10,17 +16,73 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(app *fiber.App, db *gorm.DB, jwtSecret string, logger *zap.Logger) {
+func SetupRoutes(app *fiber.App, db *gorm.DB, cfg *config.Config, objectStore storage.ObjectStore, logger *zap.Logger) {
 	// Initialize repositories
 	userRepo := repositories.NewUserRepository(db)
 	sessionsRepo := repositories.NewSessionRepository(db)
+	roleRepo := repositories.NewRoleRepository(db)
+	mfaRepo := repositories.NewMFARepository(db)
+	identityRepo := repositories.NewIdentityRepository(db)
+	organizerRepo := repositories.NewOrganizerRepository(db)
 
 	// Initialize services
-	userService := services.NewUserService(userRepo, sessionsRepo) 
-	userHandler := handlers.NewUserHandler(userService, logger, jwtSecret)
+	userService := services.NewUserService(userRepo, sessionsRepo, roleRepo, mfaRepo)
+	userHandler := handlers.NewUserHandler(userService, logger, cfg.PasetoSecret)
+	mfaService := services.NewMFAService(userRepo, sessionsRepo, roleRepo, mfaRepo, cfg.TOTPIssuer)
+	mfaHandler := handlers.NewMFAHandler(mfaService, logger, cfg.PasetoSecret)
+
+	oidcClients := make([]*oidc.Client, 0, len(cfg.OIDCProviders))
+	for _, provider := range cfg.OIDCProviders {
+		oidcClients = append(oidcClients, oidc.NewClient(oidc.ProviderConfig{
+			Name:         provider.Name,
+			IssuerURL:    provider.IssuerURL,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  provider.RedirectURL,
+		}, nil))
+	}
+	oidcService := services.NewOIDCService(oidcClients, userRepo, sessionsRepo, roleRepo, mfaRepo, identityRepo)
+	oidcHandler := handlers.NewOIDCHandler(oidcService, logger, cfg.PasetoSecret)
+	profileService := services.NewProfileService(userRepo, objectStore)
+	profileHandler := handlers.NewProfileHandler(profileService, logger)
+	organizerService := services.NewOrganizerService(userRepo, organizerRepo)
+	organizerHandler := handlers.NewOrganizerHandler(organizerService, logger)
+
+	requireAuth := middleware.RequireAuth(cfg.PasetoSecret, logger)
+	requireEnrollAuth := middleware.RequireAuth(cfg.PasetoSecret, logger, utils.PurposeMFAEnroll)
+	requireAdmin := middleware.RequireRole(roleRepo, logger, models.RoleAdmin)
 
 	// Setup routes
 	app.Post("/users/register", userHandler.CreateNewUser)
 	app.Post("/users/login", userHandler.LoginUser)
-	app.Get("/health", userHandler.GetHealthStatus)
+	app.Post("/users/login/mfa", mfaHandler.VerifyLogin)
+
+	// Social login through external OpenID Connect providers
+	app.Get("/users/oidc/:provider/login", oidcHandler.Login)
+	app.Get("/users/oidc/:provider/callback", oidcHandler.Callback)
+
+	// Profile management for the authenticated user
+	me := app.Group("/users/me", requireAuth)
+	me.Get("/", profileHandler.GetProfile)
+	me.Patch("/", profileHandler.UpdateProfile)
+	me.Delete("/", profileHandler.DeleteAccount)
+	me.Put("/profile-picture", profileHandler.UploadProfilePicture)
+	me.Get("/organizer-dashboard", organizerHandler.GetDashboard)
+
+	// Public organizer profiles
+	app.Get("/users/organizers/:id", organizerHandler.GetPublicProfile)
+
+	// Uploaded files are served by the service itself when stored on local disk
+	if cfg.Storage.Backend == "local" {
+		app.Static("/uploads", cfg.Storage.LocalDir)
+	}
+
+	// MFA enrolment also accepts the enrolment token handed out at login
+	app.Post("/users/mfa/totp/enroll", requireEnrollAuth, mfaHandler.EnrollTOTP)
+	app.Post("/users/mfa/totp/confirm", requireEnrollAuth, mfaHandler.ConfirmTOTP)
+
+	// Admin routes
+	admin := app.Group("/users/admin", requireAuth, requireAdmin)
+	admin.Get("/mfa-policies", mfaHandler.GetPolicies)
+	admin.Put("/mfa-policies/:role", mfaHandler.SetPolicy)
 }
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"user-service/internal/dto"
+	"user-service/internal/models"
+	"user-service/internal/pkg/utils"
+	"user-service/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrMFAAlreadyEnabled  = errors.New("multi-factor authentication is already enabled")
+	ErrMFANotEnrolled     = errors.New("multi-factor authentication enrolment has not been started")
+	ErrInvalidMFACode     = errors.New("invalid verification code")
+	ErrInvalidMFAToken    = errors.New("invalid or expired mfa token")
+	ErrInvalidRole        = errors.New("invalid role")
+	ErrMissingMFAResponse = errors.New("either a code or a recovery code is required")
+)
+
+const (
+	recoveryCodeCount = 10
+	// maxMFAChallengeAttempts is how many codes can be tried with one challenge
+	// token before the user has to sign in again
+	maxMFAChallengeAttempts = 5
+	// totpPeriod is the step length totp.Generate uses
+	totpPeriod = 30
+)
+
+var assignableRoles = []string{models.RoleAttendee, models.RoleOrganizer, models.RoleAdmin}
+
+type MFAService interface {
+	BeginTOTPEnrollment(userID string, logger *zap.Logger) (dto.TOTPEnrollmentResponse, error)
+	ConfirmTOTPEnrollment(userID string, input dto.ConfirmTOTPDto, issueNewSession bool, logger *zap.Logger, pasetoSecret string) (dto.ConfirmTOTPResponse, error)
+	VerifyLoginChallenge(input dto.VerifyMFADto, logger *zap.Logger, pasetoSecret string) (dto.LoginUserResponse, error)
+	GetPolicies() ([]models.MFAPolicy, error)
+	SetPolicy(role string, input dto.SetMFAPolicyDto) (*models.MFAPolicy, error)
+}
+
+type mfaService struct {
+	userRepository     repositories.UserRepository
+	sessionsRepository repositories.SessionsRepository
+	roleRepository     repositories.RoleRepository
+	mfaRepository      repositories.MFARepository
+	issuer             string
+}
+
+func NewMFAService(userRepository repositories.UserRepository,
+	sessionsRepository repositories.SessionsRepository,
+	roleRepository repositories.RoleRepository,
+	mfaRepository repositories.MFARepository,
+	issuer string) MFAService {
+	return &mfaService{
+		userRepository:     userRepository,
+		sessionsRepository: sessionsRepository,
+		roleRepository:     roleRepository,
+		mfaRepository:      mfaRepository,
+		issuer:             issuer,
+	}
+}
+
+// BeginTOTPEnrollment generates a fresh TOTP secret for the user. It only takes
+// effect once the user proves possession of it with ConfirmTOTPEnrollment.
+func (s *mfaService) BeginTOTPEnrollment(userID string, logger *zap.Logger) (dto.TOTPEnrollmentResponse, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return dto.TOTPEnrollmentResponse{}, err
+	}
+
+	existing, err := s.mfaRepository.GetUserMFA(user.ID)
+	if err != nil {
+		return dto.TOTPEnrollmentResponse{}, err
+	}
+	if existing != nil && existing.Enabled {
+		return dto.TOTPEnrollmentResponse{}, ErrMFAAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.issuer,
+		AccountName: user.Email,
+	})
+	if err != nil {
+		logger.Error("Failed to generate TOTP secret", zap.Error(err))
+		return dto.TOTPEnrollmentResponse{}, err
+	}
+
+	err = s.mfaRepository.SaveUserMFA(&models.UserMFA{
+		UserID:     user.ID,
+		TOTPSecret: key.Secret(),
+		Enabled:    false,
+	})
+	if err != nil {
+		logger.Error("Failed to store pending TOTP secret", zap.Error(err))
+		return dto.TOTPEnrollmentResponse{}, err
+	}
+
+	logger.Info("Started TOTP enrolment", zap.String("user_id", user.ID.String()))
+	return dto.TOTPEnrollmentResponse{
+		Secret:     key.Secret(),
+		OTPAuthURI: key.URL(),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment enables MFA once the first code checks out and hands back
+// the plaintext recovery codes. These are shown exactly once.
+func (s *mfaService) ConfirmTOTPEnrollment(userID string, input dto.ConfirmTOTPDto, issueNewSession bool, logger *zap.Logger, pasetoSecret string) (dto.ConfirmTOTPResponse, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return dto.ConfirmTOTPResponse{}, err
+	}
+
+	mfa, err := s.mfaRepository.GetUserMFA(user.ID)
+	if err != nil {
+		return dto.ConfirmTOTPResponse{}, err
+	}
+	if mfa == nil {
+		return dto.ConfirmTOTPResponse{}, ErrMFANotEnrolled
+	}
+	if mfa.Enabled {
+		return dto.ConfirmTOTPResponse{}, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := totpStep(input.Code, mfa.TOTPSecret, time.Now())
+	if !ok {
+		return dto.ConfirmTOTPResponse{}, ErrInvalidMFACode
+	}
+	fresh, err := s.mfaRepository.UseTOTPStep(user.ID, step)
+	if err != nil {
+		return dto.ConfirmTOTPResponse{}, err
+	}
+	if !fresh {
+		return dto.ConfirmTOTPResponse{}, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return dto.ConfirmTOTPResponse{}, err
+	}
+
+	if err := s.mfaRepository.EnableUserMFA(user.ID, hashes); err != nil {
+		logger.Error("Failed to enable MFA", zap.Error(err))
+		return dto.ConfirmTOTPResponse{}, err
+	}
+
+	logger.Info("TOTP enrolment confirmed", zap.String("user_id", user.ID.String()))
+
+	response := dto.ConfirmTOTPResponse{RecoveryCodes: codes}
+	if issueNewSession {
+		session, err := issueSession(s.sessionsRepository, s.roleRepository, user, pasetoSecret)
+		if err != nil {
+			logger.Error("Failed to create new session", zap.Error(err))
+			return dto.ConfirmTOTPResponse{}, err
+		}
+		response.Session = &session
+	}
+	return response, nil
+}
+
+// VerifyLoginChallenge completes the second login step using either a TOTP code
+// or one of the user's unused recovery codes. A challenge allows
+// maxMFAChallengeAttempts tries and can only be completed once, and a TOTP
+// code is only accepted once.
+func (s *mfaService) VerifyLoginChallenge(input dto.VerifyMFADto, logger *zap.Logger, pasetoSecret string) (dto.LoginUserResponse, error) {
+	userID, challengeID, err := utils.VerifyChallengeToken(pasetoSecret, input.MFAToken, utils.PurposeMFAChallenge)
+	if err != nil {
+		return dto.LoginUserResponse{}, ErrInvalidMFAToken
+	}
+	parsedChallengeID, err := uuid.Parse(challengeID)
+	if err != nil {
+		return dto.LoginUserResponse{}, ErrInvalidMFAToken
+	}
+
+	user, err := s.getUser(userID)
+	if err != nil {
+		return dto.LoginUserResponse{}, err
+	}
+
+	allowed, err := s.mfaRepository.TakeChallengeAttempt(parsedChallengeID, user.ID, maxMFAChallengeAttempts)
+	if err != nil {
+		return dto.LoginUserResponse{}, err
+	}
+	if !allowed {
+		logger.Warn("MFA challenge used up or expired", zap.String("user_id", user.ID.String()))
+		return dto.LoginUserResponse{}, ErrInvalidMFAToken
+	}
+
+	mfa, err := s.mfaRepository.GetUserMFA(user.ID)
+	if err != nil {
+		return dto.LoginUserResponse{}, err
+	}
+	if mfa == nil || !mfa.Enabled {
+		return dto.LoginUserResponse{}, ErrMFANotEnrolled
+	}
+
+	switch {
+	case input.Code != "":
+		step, ok := totpStep(input.Code, mfa.TOTPSecret, time.Now())
+		if !ok {
+			logger.Warn("Invalid TOTP code at login", zap.String("user_id", user.ID.String()))
+			return dto.LoginUserResponse{}, ErrInvalidMFACode
+		}
+		fresh, err := s.mfaRepository.UseTOTPStep(user.ID, step)
+		if err != nil {
+			return dto.LoginUserResponse{}, err
+		}
+		if !fresh {
+			logger.Warn("Replayed TOTP code at login", zap.String("user_id", user.ID.String()))
+			return dto.LoginUserResponse{}, ErrInvalidMFACode
+		}
+	case input.RecoveryCode != "":
+		used, err := s.mfaRepository.UseRecoveryCode(user.ID, hashRecoveryCode(input.RecoveryCode))
+		if err != nil {
+			return dto.LoginUserResponse{}, err
+		}
+		if !used {
+			logger.Warn("Invalid recovery code at login", zap.String("user_id", user.ID.String()))
+			return dto.LoginUserResponse{}, ErrInvalidMFACode
+		}
+		logger.Info("Recovery code used", zap.String("user_id", user.ID.String()))
+	default:
+		return dto.LoginUserResponse{}, ErrMissingMFAResponse
+	}
+
+	consumed, err := s.mfaRepository.ConsumeChallenge(parsedChallengeID)
+	if err != nil {
+		return dto.LoginUserResponse{}, err
+	}
+	if !consumed {
+		return dto.LoginUserResponse{}, ErrInvalidMFAToken
+	}
+
+	session, err := issueSession(s.sessionsRepository, s.roleRepository, user, pasetoSecret)
+	if err != nil {
+		logger.Error("Failed to create new session", zap.Error(err))
+		return dto.LoginUserResponse{}, err
+	}
+	return session, nil
+}
+
+func (s *mfaService) GetPolicies() ([]models.MFAPolicy, error) {
+	return s.mfaRepository.GetPolicies()
+}
+
+func (s *mfaService) SetPolicy(role string, input dto.SetMFAPolicyDto) (*models.MFAPolicy, error) {
+	role = strings.ToLower(strings.TrimSpace(role))
+	if !slices.Contains(assignableRoles, role) {
+		return nil, ErrInvalidRole
+	}
+
+	policy := &models.MFAPolicy{Role: role, RequireMFA: input.RequireMFA}
+	if err := s.mfaRepository.SavePolicy(policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (s *mfaService) getUser(userID string) (*models.User, error) {
+	parsedID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+	return s.userRepository.GetUserById(parsedID)
+}
+
+// mfaRequiredByPolicy reports whether any of the user's roles has MFA enforced
+func mfaRequiredByPolicy(roleRepository repositories.RoleRepository, mfaRepository repositories.MFARepository, userID uuid.UUID) (bool, error) {
+	roles, err := roleRepository.GetUserRoles(userID)
+	if err != nil {
+		return false, err
+	}
+
+	policies, err := mfaRepository.GetPoliciesForRoles(roles)
+	if err != nil {
+		return false, err
+	}
+
+	for _, policy := range policies {
+		if policy.RequireMFA {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// totpStep returns the time step code was generated in, allowing a step of
+// clock skew either way as totp.Validate does
+func totpStep(code, secret string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	opts := totp.ValidateOpts{Period: totpPeriod, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+	for _, skew := range []int64{0, -1, 1} {
+		at := now.Add(time.Duration(skew*totpPeriod) * time.Second)
+		expected, err := totp.GenerateCodeCustom(secret, at, opts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(code), []byte(expected)) == 1 {
+			return at.Unix() / totpPeriod, true
+		}
+	}
+	return 0, false
+}
+
+// generateRecoveryCodes returns n codes formatted as xxxxx-xxxxx along with their hashes
+func generateRecoveryCodes(n int) ([]string, []string, error) {
+	codes := make([]string, 0, n)
+	hashes := make([]string, 0, n)
+	for range n {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf))[:10]
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode normalises a recovery code before hashing so that case and
+// separators typed by the user don't matter
+func hashRecoveryCode(code string) string {
+	normalised := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalised))
+	return hex.EncodeToString(sum[:])
+}
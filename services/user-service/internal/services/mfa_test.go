@@ -0,0 +1,46 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+func TestTOTPStep(t *testing.T) {
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: "Entritts", AccountName: "user@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	opts := totp.ValidateOpts{Period: totpPeriod, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+	codeAt := func(at time.Time) string {
+		code, err := totp.GenerateCodeCustom(key.Secret(), at, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name string
+		code string
+		step int64
+		ok   bool
+	}{
+		{name: "current step", code: codeAt(now), step: now.Unix() / totpPeriod, ok: true},
+		{name: "previous step", code: codeAt(now.Add(-totpPeriod * time.Second)), step: now.Unix()/totpPeriod - 1, ok: true},
+		{name: "next step", code: " " + codeAt(now.Add(totpPeriod*time.Second)) + " ", step: now.Unix()/totpPeriod + 1, ok: true},
+		{name: "too old", code: codeAt(now.Add(-2 * totpPeriod * time.Second))},
+		{name: "not a code", code: "abcdef"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := totpStep(tt.code, key.Secret(), now)
+			if ok != tt.ok || step != tt.step {
+				t.Fatalf("expected (%d, %t), got (%d, %t)", tt.step, tt.ok, step, ok)
+			}
+		})
+	}
+}
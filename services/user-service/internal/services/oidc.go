@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+	"user-service/internal/dto"
+	"user-service/internal/models"
+	"user-service/internal/pkg/oidc"
+	"user-service/internal/repositories"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownProvider     = errors.New("unknown identity provider")
+	ErrInvalidOIDCState    = errors.New("invalid or expired login state")
+	ErrOIDCEmailMissing    = errors.New("identity provider did not return an email address")
+	ErrOIDCEmailUnverified = errors.New("identity provider has not verified this email address")
+)
+
+const oidcStateExpiry = 10 * time.Minute
+
+type OIDCService interface {
+	BeginLogin(ctx context.Context, provider string) (authURL, binding string, err error)
+	CompleteLogin(ctx context.Context, provider, state, binding, code string, logger *zap.Logger, pasetoSecret string) (dto.LoginUserResponse, error)
+}
+
+type oidcService struct {
+	clients            map[string]*oidc.Client
+	userRepository     repositories.UserRepository
+	sessionsRepository repositories.SessionsRepository
+	roleRepository     repositories.RoleRepository
+	mfaRepository      repositories.MFARepository
+	identityRepository repositories.IdentityRepository
+}
+
+func NewOIDCService(clients []*oidc.Client,
+	userRepository repositories.UserRepository,
+	sessionsRepository repositories.SessionsRepository,
+	roleRepository repositories.RoleRepository,
+	mfaRepository repositories.MFARepository,
+	identityRepository repositories.IdentityRepository) OIDCService {
+	byName := make(map[string]*oidc.Client, len(clients))
+	for _, client := range clients {
+		byName[client.Name()] = client
+	}
+	return &oidcService{
+		clients:            byName,
+		userRepository:     userRepository,
+		sessionsRepository: sessionsRepository,
+		roleRepository:     roleRepository,
+		mfaRepository:      mfaRepository,
+		identityRepository: identityRepository,
+	}
+}
+
+// BeginLogin stores a fresh state, nonce and PKCE verifier and returns the
+// provider's authorization URL. binding must be kept by the browser starting
+// the login, e.g. in a cookie, and handed back to CompleteLogin: it ties the
+// state to that browser, so nobody can finish their login in someone else's.
+func (s *oidcService) BeginLogin(ctx context.Context, provider string) (string, string, error) {
+	client, ok := s.clients[provider]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	loginState := &models.OIDCLoginState{
+		State:        state,
+		Provider:     provider,
+		Nonce:        nonce,
+		CodeVerifier: oidc.GenerateVerifier(),
+		ExpiresAt:    time.Now().Add(oidcStateExpiry),
+	}
+
+	authURL, err := client.AuthCodeURL(ctx, loginState.State, loginState.Nonce, loginState.CodeVerifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := s.identityRepository.SaveLoginState(loginState); err != nil {
+		return "", "", err
+	}
+	return authURL, stateBinding(state), nil
+}
+
+// CompleteLogin handles the provider callback, resolves the local user and
+// finishes the login exactly like a password login would
+func (s *oidcService) CompleteLogin(ctx context.Context, provider, state, binding, code string, logger *zap.Logger, pasetoSecret string) (dto.LoginUserResponse, error) {
+	client, ok := s.clients[provider]
+	if !ok {
+		return dto.LoginUserResponse{}, ErrUnknownProvider
+	}
+
+	// Checked before consuming the state, so a forged callback can't burn it
+	if subtle.ConstantTimeCompare([]byte(binding), []byte(stateBinding(state))) != 1 {
+		return dto.LoginUserResponse{}, ErrInvalidOIDCState
+	}
+
+	loginState, err := s.identityRepository.ConsumeLoginState(state)
+	if err != nil {
+		return dto.LoginUserResponse{}, err
+	}
+	if loginState == nil || loginState.Provider != provider || time.Now().After(loginState.ExpiresAt) {
+		return dto.LoginUserResponse{}, ErrInvalidOIDCState
+	}
+
+	claims, err := client.Exchange(ctx, code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		logger.Warn("OIDC code exchange failed", zap.String("provider", provider), zap.Error(err))
+		return dto.LoginUserResponse{}, err
+	}
+
+	user, err := s.resolveUser(provider, claims, logger)
+	if err != nil {
+		return dto.LoginUserResponse{}, err
+	}
+
+	return completeLogin(s.sessionsRepository, s.roleRepository, s.mfaRepository, user, pasetoSecret, logger)
+}
+
+// resolveUser finds the user linked to the external identity, links an existing
+// user with the same verified email, or creates a new user
+func (s *oidcService) resolveUser(provider string, claims *oidc.Claims, logger *zap.Logger) (*models.User, error) {
+	identity, err := s.identityRepository.GetIdentity(provider, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		return s.userRepository.GetUserById(identity.UserID)
+	}
+
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	if email == "" {
+		return nil, ErrOIDCEmailMissing
+	}
+
+	newIdentity := &models.UserIdentity{
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    email,
+	}
+
+	user, err := s.userRepository.GetUserByEmail(email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if user != nil {
+		// Only link to an existing account when the provider vouches for the address
+		if !claims.EmailVerified {
+			return nil, ErrOIDCEmailUnverified
+		}
+		newIdentity.UserID = user.ID
+		if err := s.identityRepository.CreateIdentity(newIdentity); err != nil {
+			return nil, err
+		}
+		logger.Info("Linked external identity to existing user",
+			zap.String("provider", provider),
+			zap.String("user_id", user.ID.String()),
+		)
+		return user, nil
+	}
+
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName, lastName, _ = strings.Cut(claims.Name, " ")
+	}
+
+	newUser := &models.User{
+		FirstName:     firstName,
+		LastName:      lastName,
+		Email:         email,
+		EmailVerified: claims.EmailVerified,
+	}
+	if len(claims.Picture) <= 255 {
+		newUser.ProfilePicURL = claims.Picture
+	}
+
+	if err := s.identityRepository.CreateUserWithIdentity(newUser, newIdentity); err != nil {
+		logger.Error("Failed to create user from external identity", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("Created user from external identity",
+		zap.String("provider", provider),
+		zap.String("user_id", newUser.ID.String()),
+	)
+	return newUser, nil
+}
+
+// stateBinding is what the browser keeps for a state. Only a hash, so the
+// state itself never sits in a cookie.
+func stateBinding(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"user-service/internal/models"
+	"user-service/internal/pkg/oidc"
+	"user-service/internal/pkg/oidc/oidctest"
+	"user-service/internal/repositories"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const testPasetoSecret = "0123456789abcdef0123456789abcdef"
+
+// fakeIdentities keeps users, identities and login states in memory. The
+// embedded repositories are nil: only the methods the OIDC flow needs are
+// implemented.
+type fakeIdentities struct {
+	repositories.UserRepository
+	repositories.SessionsRepository
+	repositories.RoleRepository
+	repositories.MFARepository
+
+	users      map[uuid.UUID]*models.User
+	identities []models.UserIdentity
+	states     map[string]*models.OIDCLoginState
+}
+
+func newFakeIdentities() *fakeIdentities {
+	return &fakeIdentities{
+		users:  make(map[uuid.UUID]*models.User),
+		states: make(map[string]*models.OIDCLoginState),
+	}
+}
+
+func (f *fakeIdentities) GetUserByEmail(email string) (*models.User, error) {
+	for _, user := range f.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeIdentities) GetUserById(id uuid.UUID) (*models.User, error) {
+	if user, ok := f.users[id]; ok {
+		return user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeIdentities) CreateNewSession(session *models.UserSession) (*models.UserSession, error) {
+	session.ID = uuid.New()
+	return session, nil
+}
+
+func (f *fakeIdentities) GetUserRoles(userID uuid.UUID) ([]string, error) {
+	return []string{"user"}, nil
+}
+
+func (f *fakeIdentities) GetUserMFA(userID uuid.UUID) (*models.UserMFA, error) {
+	return nil, nil
+}
+
+func (f *fakeIdentities) GetPoliciesForRoles(roles []string) ([]models.MFAPolicy, error) {
+	return nil, nil
+}
+
+func (f *fakeIdentities) GetIdentity(provider, subject string) (*models.UserIdentity, error) {
+	for _, identity := range f.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeIdentities) CreateIdentity(identity *models.UserIdentity) error {
+	f.identities = append(f.identities, *identity)
+	return nil
+}
+
+func (f *fakeIdentities) CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error {
+	user.ID = uuid.New()
+	f.users[user.ID] = user
+	identity.UserID = user.ID
+	return f.CreateIdentity(identity)
+}
+
+func (f *fakeIdentities) SaveLoginState(state *models.OIDCLoginState) error {
+	f.states[state.State] = state
+	return nil
+}
+
+func (f *fakeIdentities) ConsumeLoginState(state string) (*models.OIDCLoginState, error) {
+	loginState := f.states[state]
+	delete(f.states, state)
+	return loginState, nil
+}
+
+func newTestOIDCService(t *testing.T) (OIDCService, *fakeIdentities, *oidctest.Provider) {
+	provider := oidctest.NewProvider(t, "entritts")
+	client := oidc.NewClient(oidc.ProviderConfig{
+		Name:        "stub",
+		IssuerURL:   provider.Issuer(),
+		ClientID:    "entritts",
+		RedirectURL: "http://localhost/callback",
+	}, nil)
+	repo := newFakeIdentities()
+	return NewOIDCService([]*oidc.Client{client}, repo, repo, repo, repo, repo), repo, provider
+}
+
+// signIn runs BeginLogin and the provider's side of the redirect, returning
+// what the callback receives
+func signIn(t *testing.T, service OIDCService, provider *oidctest.Provider, login oidctest.Login) (code, state, binding string) {
+	t.Helper()
+	authURL, binding, err := service.BeginLogin(context.Background(), "stub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state = provider.Authorize(t, authURL, login)
+	return code, state, binding
+}
+
+func TestCompleteLoginRejectsState(t *testing.T) {
+	service, repo, provider := newTestOIDCService(t)
+	logger := zap.NewNop()
+	ctx := context.Background()
+	login := oidctest.Login{Subject: "sub-1", Email: "ada@example.com", EmailVerified: true}
+
+	code, state, binding := signIn(t, service, provider, login)
+	if _, err := service.CompleteLogin(ctx, "stub", "forged", binding, code, logger, testPasetoSecret); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("unknown state: error = %v, want ErrInvalidOIDCState", err)
+	}
+
+	if _, err := service.CompleteLogin(ctx, "stub", state, binding, code, logger, testPasetoSecret); err != nil {
+		t.Fatal(err)
+	}
+	// States are single use
+	if _, err := service.CompleteLogin(ctx, "stub", state, binding, code, logger, testPasetoSecret); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("replayed state: error = %v, want ErrInvalidOIDCState", err)
+	}
+
+	code, state, binding = signIn(t, service, provider, login)
+	repo.states[state].ExpiresAt = time.Now().Add(-time.Second)
+	if _, err := service.CompleteLogin(ctx, "stub", state, binding, code, logger, testPasetoSecret); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("expired state: error = %v, want ErrInvalidOIDCState", err)
+	}
+}
+
+func TestCompleteLoginRejectsOtherBrowser(t *testing.T) {
+	service, repo, provider := newTestOIDCService(t)
+	logger := zap.NewNop()
+	ctx := context.Background()
+
+	// The attacker signs in and sends the victim their callback URL; the
+	// victim's browser has no binding, or one from a login of its own
+	code, state, binding := signIn(t, service, provider, oidctest.Login{Subject: "attacker", Email: "eve@example.com", EmailVerified: true})
+	_, _, victimBinding := signIn(t, service, provider, oidctest.Login{Subject: "victim", Email: "ada@example.com", EmailVerified: true})
+
+	for _, other := range []string{"", victimBinding} {
+		if _, err := service.CompleteLogin(ctx, "stub", state, other, code, logger, testPasetoSecret); !errors.Is(err, ErrInvalidOIDCState) {
+			t.Fatalf("binding %q: error = %v, want ErrInvalidOIDCState", other, err)
+		}
+	}
+	if len(repo.users) != 0 {
+		t.Fatalf("created %d users", len(repo.users))
+	}
+
+	// A forged callback doesn't use up the state of the browser that owns it
+	if _, err := service.CompleteLogin(ctx, "stub", state, binding, code, logger, testPasetoSecret); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompleteLoginRejectsPKCEMismatch(t *testing.T) {
+	service, repo, provider := newTestOIDCService(t)
+	code, state, binding := signIn(t, service, provider, oidctest.Login{Subject: "sub-1", Email: "ada@example.com", EmailVerified: true})
+	repo.states[state].CodeVerifier = oidc.GenerateVerifier()
+
+	_, err := service.CompleteLogin(context.Background(), "stub", state, binding, code, zap.NewNop(), testPasetoSecret)
+	if err == nil || !strings.Contains(err.Error(), "code exchange failed") {
+		t.Fatalf("error = %v, want a failed code exchange", err)
+	}
+	if len(repo.users) != 0 {
+		t.Fatalf("created %d users", len(repo.users))
+	}
+}
+
+func TestCompleteLoginLinksAccounts(t *testing.T) {
+	existing := &models.User{ID: uuid.New(), FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"}
+
+	tests := []struct {
+		name  string
+		login oidctest.Login
+		// wantErr is nil when the login should succeed
+		wantErr error
+		// linked is whether the identity ends up on the existing user
+		linked bool
+	}{
+		{
+			name:   "verified email links the existing user",
+			login:  oidctest.Login{Subject: "sub-1", Email: " ADA@example.com", EmailVerified: true},
+			linked: true,
+		},
+		{
+			name:    "unverified email is not linked",
+			login:   oidctest.Login{Subject: "sub-1", Email: "ada@example.com"},
+			wantErr: ErrOIDCEmailUnverified,
+		},
+		{
+			name:    "missing email",
+			login:   oidctest.Login{Subject: "sub-1"},
+			wantErr: ErrOIDCEmailMissing,
+		},
+		{
+			name:  "new email creates a user",
+			login: oidctest.Login{Subject: "sub-1", Email: "grace@example.com", EmailVerified: true, Name: "Grace Hopper"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, repo, provider := newTestOIDCService(t)
+			user := *existing
+			repo.users[user.ID] = &user
+
+			code, state, binding := signIn(t, service, provider, tt.login)
+			response, err := service.CompleteLogin(context.Background(), "stub", state, binding, code, zap.NewNop(), testPasetoSecret)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				if len(repo.identities) != 0 {
+					t.Fatalf("linked %d identities", len(repo.identities))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.AccessToken == "" {
+				t.Fatal("no session was issued")
+			}
+
+			if len(repo.identities) != 1 {
+				t.Fatalf("linked %d identities, want 1", len(repo.identities))
+			}
+			identity := repo.identities[0]
+			if identity.Provider != "stub" || identity.Subject != "sub-1" {
+				t.Fatalf("identity = %+v", identity)
+			}
+			if linked := identity.UserID == existing.ID; linked != tt.linked {
+				t.Fatalf("identity linked to the existing user: %t, want %t", linked, tt.linked)
+			}
+			if !tt.linked {
+				created := repo.users[identity.UserID]
+				if created == nil || created.Email != "grace@example.com" || created.FirstName != "Grace" || created.LastName != "Hopper" {
+					t.Fatalf("created user = %+v", created)
+				}
+			}
+
+			// The next login finds the user through the identity, whatever the email says
+			relogin := tt.login
+			relogin.Email = "changed@example.com"
+			users := len(repo.users)
+			code, state, binding = signIn(t, service, provider, relogin)
+			if _, err := service.CompleteLogin(context.Background(), "stub", state, binding, code, zap.NewNop(), testPasetoSecret); err != nil {
+				t.Fatal(err)
+			}
+			if len(repo.identities) != 1 || len(repo.users) != users {
+				t.Fatalf("second login added identities or users: %d identities, %d users", len(repo.identities), len(repo.users))
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-service/internal/dto"
+	"user-service/internal/models"
+	"user-service/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var ErrOrganizerNotFound = errors.New("organizer not found")
+
+const upcomingEventsLimit = 10
+
+type OrganizerService interface {
+	RecordEventCreated(ctx context.Context, organizerID, eventID uuid.UUID, title string, startDate *time.Time) (bool, error)
+	RecordEventDeleted(ctx context.Context, eventID uuid.UUID) (bool, error)
+	RecordEventRescheduled(ctx context.Context, eventID uuid.UUID, startDate time.Time) error
+	RecordTicketSale(ctx context.Context, orderID, organizerID, eventID uuid.UUID, quantity int, amount decimal.Decimal) (bool, error)
+	GetPublicProfile(organizerID string) (dto.OrganizerProfileResponse, error)
+	GetDashboard(organizerID string) (dto.OrganizerDashboardResponse, error)
+	WithTx(tx *gorm.DB) OrganizerService
+}
+
+type organizerService struct {
+	userRepository      repositories.UserRepository
+	organizerRepository repositories.OrganizerRepository
+}
+
+func NewOrganizerService(userRepository repositories.UserRepository, organizerRepository repositories.OrganizerRepository) OrganizerService {
+	return &organizerService{
+		userRepository:      userRepository,
+		organizerRepository: organizerRepository,
+	}
+}
+
+// WithTx returns a service whose stats updates join the given transaction, e.g.
+// the one recording a consumed message in the inbox
+func (s *organizerService) WithTx(tx *gorm.DB) OrganizerService {
+	return &organizerService{
+		userRepository:      s.userRepository,
+		organizerRepository: s.organizerRepository.WithTx(tx),
+	}
+}
+
+// RecordEventCreated counts an event towards its organizer's stats. Safe to call
+// repeatedly for the same event; only the first call has an effect.
+func (s *organizerService) RecordEventCreated(ctx context.Context, organizerID, eventID uuid.UUID, title string, startDate *time.Time) (bool, error) {
+	return s.organizerRepository.RecordEventCreated(&models.OrganizerEvent{
+		EventID:     eventID,
+		OrganizerID: organizerID,
+		Title:       title,
+		StartDate:   startDate,
+	})
+}
+
+func (s *organizerService) RecordEventDeleted(ctx context.Context, eventID uuid.UUID) (bool, error) {
+	return s.organizerRepository.RecordEventDeleted(eventID)
+}
+
+func (s *organizerService) RecordEventRescheduled(ctx context.Context, eventID uuid.UUID, startDate time.Time) error {
+	return s.organizerRepository.UpdateEventStartDate(eventID, startDate)
+}
+
+// RecordTicketSale adds a completed order to the organizer's totals, once per order
+func (s *organizerService) RecordTicketSale(ctx context.Context, orderID, organizerID, eventID uuid.UUID, quantity int, amount decimal.Decimal) (bool, error) {
+	return s.organizerRepository.RecordSale(&models.OrganizerSale{
+		OrderID:     orderID,
+		OrganizerID: organizerID,
+		EventID:     eventID,
+		Quantity:    quantity,
+		Amount:      amount,
+	})
+}
+
+func (s *organizerService) GetPublicProfile(organizerID string) (dto.OrganizerProfileResponse, error) {
+	user, err := s.getOrganizer(organizerID)
+	if err != nil {
+		return dto.OrganizerProfileResponse{}, err
+	}
+
+	stats, upcomingCount, upcoming, err := s.loadStats(user.ID)
+	if err != nil {
+		return dto.OrganizerProfileResponse{}, err
+	}
+	if stats == nil && upcomingCount == 0 {
+		return dto.OrganizerProfileResponse{}, ErrOrganizerNotFound
+	}
+
+	response := dto.OrganizerProfileResponse{
+		OrganizerID:    user.ID,
+		FirstName:      user.FirstName,
+		LastName:       user.LastName,
+		ProfilePicURL:  user.ProfilePicURL,
+		UpcomingEvents: upcomingCount,
+		MemberSince:    user.CreatedAt,
+		Upcoming:       upcoming,
+	}
+	if stats != nil {
+		response.EventsHosted = stats.EventsHosted
+	}
+	return response, nil
+}
+
+func (s *organizerService) GetDashboard(organizerID string) (dto.OrganizerDashboardResponse, error) {
+	user, err := s.getOrganizer(organizerID)
+	if err != nil {
+		return dto.OrganizerDashboardResponse{}, err
+	}
+
+	stats, upcomingCount, upcoming, err := s.loadStats(user.ID)
+	if err != nil {
+		return dto.OrganizerDashboardResponse{}, err
+	}
+
+	response := dto.OrganizerDashboardResponse{
+		OrganizerID:    user.ID,
+		UpcomingEvents: upcomingCount,
+		Revenue:        decimal.Zero,
+		Upcoming:       upcoming,
+	}
+	if stats != nil {
+		response.EventsHosted = stats.EventsHosted
+		response.TicketsSold = stats.TicketsSold
+		response.Revenue = stats.Revenue
+		response.UpdatedAt = &stats.UpdatedAt
+	}
+	return response, nil
+}
+
+func (s *organizerService) loadStats(organizerID uuid.UUID) (*models.OrganizerStats, int64, []dto.OrganizerEventSummary, error) {
+	stats, err := s.organizerRepository.GetStats(organizerID)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	upcomingCount, err := s.organizerRepository.CountUpcomingEvents(organizerID)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	events, err := s.organizerRepository.GetUpcomingEvents(organizerID, upcomingEventsLimit)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	upcoming := make([]dto.OrganizerEventSummary, 0, len(events))
+	for _, event := range events {
+		upcoming = append(upcoming, dto.OrganizerEventSummary{
+			EventID:   event.EventID,
+			Title:     event.Title,
+			StartDate: event.StartDate,
+		})
+	}
+	return stats, upcomingCount, upcoming, nil
+}
+
+func (s *organizerService) getOrganizer(organizerID string) (*models.User, error) {
+	parsedID, err := uuid.Parse(organizerID)
+	if err != nil {
+		return nil, ErrOrganizerNotFound
+	}
+
+	user, err := s.userRepository.GetUserById(parsedID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrganizerNotFound
+		}
+		return nil, err
+	}
+	if user.AnonymisedAt != nil {
+		return nil, ErrOrganizerNotFound
+	}
+	return user, nil
+}
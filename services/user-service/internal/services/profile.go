@@ -0,0 +1,236 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"user-service/internal/dto"
+	"user-service/internal/models"
+	"user-service/internal/pkg/storage"
+	"user-service/internal/repositories"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUserNotFound           = errors.New("user not found")
+	ErrPhoneNumberTaken       = errors.New("phone number is already in use")
+	ErrInvalidPhoneNumber     = errors.New("phone number must be in E.164 format, e.g. +2348012345678")
+	ErrInvalidProfileUpdate   = errors.New("first and last name must be between 2 and 50 characters")
+	ErrUnsupportedImageType   = errors.New("profile picture must be a JPEG, PNG or WebP image")
+	ErrProfilePictureTooLarge = errors.New("profile picture must be 2MB or smaller")
+)
+
+// e164Pattern matches a + followed by at most 15 digits, the longest E.164
+// number and what users.phone_number is sized for
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// MaxProfilePictureSize keeps uploads under Fiber's default 4MB body limit
+const MaxProfilePictureSize = 2 * 1024 * 1024
+
+var profilePictureTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+type ProfileService interface {
+	GetProfile(userID string) (dto.UserProfileResponse, error)
+	UpdateProfile(userID string, input dto.UpdateProfileDto, logger *zap.Logger) (dto.UserProfileResponse, error)
+	UploadProfilePicture(ctx context.Context, userID string, body io.Reader, size int64, logger *zap.Logger) (dto.UserProfileResponse, error)
+	DeleteAccount(ctx context.Context, userID string, logger *zap.Logger) error
+}
+
+type profileService struct {
+	userRepository repositories.UserRepository
+	objectStore    storage.ObjectStore
+}
+
+func NewProfileService(userRepository repositories.UserRepository, objectStore storage.ObjectStore) ProfileService {
+	return &profileService{
+		userRepository: userRepository,
+		objectStore:    objectStore,
+	}
+}
+
+func (s *profileService) GetProfile(userID string) (dto.UserProfileResponse, error) {
+	user, err := s.getActiveUser(userID)
+	if err != nil {
+		return dto.UserProfileResponse{}, err
+	}
+	return toProfileResponse(user), nil
+}
+
+func (s *profileService) UpdateProfile(userID string, input dto.UpdateProfileDto, logger *zap.Logger) (dto.UserProfileResponse, error) {
+	user, err := s.getActiveUser(userID)
+	if err != nil {
+		return dto.UserProfileResponse{}, err
+	}
+
+	updates := map[string]interface{}{}
+	if input.FirstName != nil {
+		name := strings.TrimSpace(*input.FirstName)
+		if len(name) < 2 || len(name) > 50 {
+			return dto.UserProfileResponse{}, ErrInvalidProfileUpdate
+		}
+		updates["first_name"] = name
+	}
+	if input.LastName != nil {
+		name := strings.TrimSpace(*input.LastName)
+		if len(name) < 2 || len(name) > 50 {
+			return dto.UserProfileResponse{}, ErrInvalidProfileUpdate
+		}
+		updates["last_name"] = name
+	}
+	if input.PhoneNumber != nil {
+		phoneNumber := strings.TrimSpace(*input.PhoneNumber)
+		if !e164Pattern.MatchString(phoneNumber) {
+			return dto.UserProfileResponse{}, ErrInvalidPhoneNumber
+		}
+		existing, err := s.userRepository.GetUserByPhoneNumber(phoneNumber)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return dto.UserProfileResponse{}, err
+		}
+		if existing != nil && existing.ID != user.ID {
+			return dto.UserProfileResponse{}, ErrPhoneNumberTaken
+		}
+		updates["phone_number"] = phoneNumber
+	}
+
+	if len(updates) == 0 {
+		return toProfileResponse(user), nil
+	}
+
+	updated, err := s.userRepository.UpdateUser(user.ID, updates)
+	if err != nil {
+		logger.Error("Failed to update profile", zap.String("user_id", user.ID.String()), zap.Error(err))
+		return dto.UserProfileResponse{}, err
+	}
+
+	logger.Info("Profile updated", zap.String("user_id", user.ID.String()))
+	return toProfileResponse(updated), nil
+}
+
+// UploadProfilePicture sniffs the image type, stores it in the object store and
+// replaces any previously uploaded picture
+func (s *profileService) UploadProfilePicture(ctx context.Context, userID string, body io.Reader, size int64, logger *zap.Logger) (dto.UserProfileResponse, error) {
+	user, err := s.getActiveUser(userID)
+	if err != nil {
+		return dto.UserProfileResponse{}, err
+	}
+
+	if size > MaxProfilePictureSize {
+		return dto.UserProfileResponse{}, ErrProfilePictureTooLarge
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(body, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return dto.UserProfileResponse{}, err
+	}
+	contentType := http.DetectContentType(head[:n])
+	extension, ok := profilePictureTypes[contentType]
+	if !ok {
+		return dto.UserProfileResponse{}, ErrUnsupportedImageType
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return dto.UserProfileResponse{}, err
+	}
+	key := "profile-pictures/" + user.ID.String() + "/" + hex.EncodeToString(suffix) + extension
+
+	url, err := s.objectStore.Put(ctx, key, io.MultiReader(bytes.NewReader(head[:n]), body), size, contentType)
+	if err != nil {
+		logger.Error("Failed to store profile picture", zap.String("user_id", user.ID.String()), zap.Error(err))
+		return dto.UserProfileResponse{}, err
+	}
+
+	updated, err := s.userRepository.UpdateUser(user.ID, map[string]interface{}{
+		"profile_pic_url": url,
+		"profile_pic_key": key,
+	})
+	if err != nil {
+		// Don't leave an orphaned object behind
+		if delErr := s.objectStore.Delete(ctx, key); delErr != nil {
+			logger.Warn("Failed to remove orphaned profile picture", zap.String("key", key), zap.Error(delErr))
+		}
+		return dto.UserProfileResponse{}, err
+	}
+
+	if user.ProfilePicKey != "" {
+		if err := s.objectStore.Delete(ctx, user.ProfilePicKey); err != nil {
+			logger.Warn("Failed to remove previous profile picture", zap.String("key", user.ProfilePicKey), zap.Error(err))
+		}
+	}
+
+	logger.Info("Profile picture updated", zap.String("user_id", user.ID.String()))
+	return toProfileResponse(updated), nil
+}
+
+// DeleteAccount anonymises the user's personal data and removes the stored picture
+func (s *profileService) DeleteAccount(ctx context.Context, userID string, logger *zap.Logger) error {
+	user, err := s.getActiveUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepository.AnonymiseUser(user.ID); err != nil {
+		logger.Error("Failed to anonymise user", zap.String("user_id", user.ID.String()), zap.Error(err))
+		return err
+	}
+
+	if user.ProfilePicKey != "" {
+		if err := s.objectStore.Delete(ctx, user.ProfilePicKey); err != nil {
+			logger.Warn("Failed to remove profile picture of deleted user", zap.String("key", user.ProfilePicKey), zap.Error(err))
+		}
+	}
+
+	logger.Info("User account deleted and anonymised", zap.String("user_id", user.ID.String()))
+	return nil
+}
+
+// getActiveUser loads a user, treating anonymised accounts as gone
+func (s *profileService) getActiveUser(userID string) (*models.User, error) {
+	parsedID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	user, err := s.userRepository.GetUserById(parsedID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.AnonymisedAt != nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func toProfileResponse(user *models.User) dto.UserProfileResponse {
+	response := dto.UserProfileResponse{
+		UserID:        user.ID,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Email:         user.Email,
+		ProfilePicURL: user.ProfilePicURL,
+		EmailVerified: user.EmailVerified,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+	}
+	if user.PhoneNumber != nil {
+		response.PhoneNumber = *user.PhoneNumber
+	}
+	return response
+}
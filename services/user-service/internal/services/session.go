@@ -0,0 +1,101 @@
+package services
+
+import (
+	"time"
+	"user-service/internal/dto"
+	"user-service/internal/models"
+	"user-service/internal/pkg/utils"
+	"user-service/internal/repositories"
+
+	"go.uber.org/zap"
+)
+
+const (
+	accessTokenExpiry  = 1 * time.Hour
+	refreshTokenExpiry = 168 * time.Hour
+	mfaTokenExpiry     = 5 * time.Minute
+)
+
+// issueSession signs the access and refresh tokens for a user and stores a new session
+func issueSession(sessionsRepository repositories.SessionsRepository,
+	roleRepository repositories.RoleRepository,
+	user *models.User, pasetoSecret string) (dto.LoginUserResponse, error) {
+	roles, err := roleRepository.GetUserRoles(user.ID)
+	if err != nil {
+		return dto.LoginUserResponse{}, err
+	}
+
+	accessToken, err := utils.SignAccessToken(pasetoSecret, user.ID.String(), user.Email, roles, accessTokenExpiry)
+	if err != nil {
+		return dto.LoginUserResponse{}, err
+	}
+
+	refreshToken, err := utils.SignPasetoToken(pasetoSecret, user.ID.String(), user.Email, refreshTokenExpiry)
+	if err != nil {
+		return dto.LoginUserResponse{}, err
+	}
+
+	// Create a new entry in the sessions table
+	newSession := models.UserSession{
+		UserID:       user.ID,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    time.Now().Add(accessTokenExpiry),
+	}
+	session, err := sessionsRepository.CreateNewSession(&newSession)
+	if err != nil {
+		return dto.LoginUserResponse{}, err
+	}
+
+	return dto.LoginUserResponse{
+		AccessToken:  accessToken,
+		SessionID:    session.ID.String(),
+		RefreshToken: refreshToken,
+		ExpiresIn:    newSession.ExpiresAt,
+	}, nil
+}
+
+// completeLogin runs once the user's first factor checked out. It hands out a
+// short lived challenge token instead of a session when a second factor is due.
+func completeLogin(sessionsRepository repositories.SessionsRepository,
+	roleRepository repositories.RoleRepository,
+	mfaRepository repositories.MFARepository,
+	user *models.User, pasetoSecret string, logger *zap.Logger) (dto.LoginUserResponse, error) {
+	mfa, err := mfaRepository.GetUserMFA(user.ID)
+	if err != nil {
+		logger.Error("Failed to load MFA settings", zap.Error(err))
+		return dto.LoginUserResponse{}, err
+	}
+	if mfa != nil && mfa.Enabled {
+		challenge, err := mfaRepository.CreateChallenge(user.ID, mfaTokenExpiry)
+		if err != nil {
+			logger.Error("Failed to create MFA challenge", zap.Error(err))
+			return dto.LoginUserResponse{}, err
+		}
+		mfaToken, err := utils.SignChallengeToken(pasetoSecret, user.ID.String(), user.Email, utils.PurposeMFAChallenge, challenge.ID.String(), mfaTokenExpiry)
+		if err != nil {
+			return dto.LoginUserResponse{}, err
+		}
+		return dto.LoginUserResponse{MFARequired: true, MFAToken: mfaToken}, nil
+	}
+
+	required, err := mfaRequiredByPolicy(roleRepository, mfaRepository, user.ID)
+	if err != nil {
+		logger.Error("Failed to evaluate MFA policy", zap.Error(err))
+		return dto.LoginUserResponse{}, err
+	}
+	if required {
+		enrollToken, err := utils.SignPurposeToken(pasetoSecret, user.ID.String(), user.Email, utils.PurposeMFAEnroll, mfaTokenExpiry)
+		if err != nil {
+			return dto.LoginUserResponse{}, err
+		}
+		return dto.LoginUserResponse{MFAEnrollmentRequired: true, MFAToken: enrollToken}, nil
+	}
+
+	response, err := issueSession(sessionsRepository, roleRepository, user, pasetoSecret)
+	if err != nil {
+		logger.Error("Failed to create new session")
+		return dto.LoginUserResponse{}, err
+	}
+	return response, nil
+}
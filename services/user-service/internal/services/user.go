@@ -3,10 +3,8 @@ package services
 import (
 	"context"
 	"errors"
-	"time"
 	"user-service/internal/dto"
 	"user-service/internal/models"
-	"user-service/internal/pkg/utils"
 	"user-service/internal/repositories"
 
 	"github.com/google/uuid"
@@ -24,22 +22,33 @@ type UserService interface {
 	CreateNewUser(input dto.CreateUserDto, logger *zap.Logger) error
 	LoginUser(input dto.LoginUserDto, logger *zap.Logger, jwtSecret string) (dto.LoginUserResponse, error)
 	GetUserByID(ctx context.Context, userID string) (*models.User, error)
-	IncrementUserEventCount(ctx context.Context, userID string) error
+	BootstrapAdmin(email string, logger *zap.Logger) error
 }
 
 type userService struct {
 	userRepository     repositories.UserRepository
 	sessionsRepository repositories.SessionsRepository
+	roleRepository     repositories.RoleRepository
+	mfaRepository      repositories.MFARepository
 }
 
-func NewUserService(userRepository repositories.UserRepository, sessionsRepository repositories.SessionsRepository) UserService {
+func NewUserService(userRepository repositories.UserRepository,
+	sessionsRepository repositories.SessionsRepository,
+	roleRepository repositories.RoleRepository,
+	mfaRepository repositories.MFARepository) UserService {
 	return &userService{
 		userRepository:     userRepository,
 		sessionsRepository: sessionsRepository,
+		roleRepository:     roleRepository,
+		mfaRepository:      mfaRepository,
 	}
 }
 
 func (s *userService) CreateNewUser(input dto.CreateUserDto, logger *zap.Logger) error {
+	if !e164Pattern.MatchString(input.PhoneNumber) {
+		return ErrInvalidPhoneNumber
+	}
+
 	// Ensure user doesn't exist beforehand
 	user, err := s.userRepository.GetUserByEmail(input.Email)
 	if err != nil {
@@ -64,7 +73,7 @@ func (s *userService) CreateNewUser(input dto.CreateUserDto, logger *zap.Logger)
 		FirstName:    input.FirstName,
 		LastName:     input.LastName,
 		Email:        input.Email,
-		PhoneNumber:  input.PhoneNumber,
+		PhoneNumber:  &input.PhoneNumber,
 		PasswordHash: string(hashedPassword),
 	}
 
@@ -104,38 +113,7 @@ func (s *userService) LoginUser(input dto.LoginUserDto, logger *zap.Logger, pase
 		return dto.LoginUserResponse{}, err
 	}
 
-	// Sign PASETO token
-	expiryTime := 1 * time.Hour
-	accessToken, err := utils.SignPasetoToken(pasetoSecret, user.ID.String(), user.Email, expiryTime)
-	if err != nil {
-		return dto.LoginUserResponse{}, err
-	}
-
-	refreshToken, err := utils.SignPasetoToken(pasetoSecret, user.ID.String(), user.Email, 168*time.Hour)
-	if err != nil {
-		return dto.LoginUserResponse{}, err
-	}
-	// Create a new entry in the sessions table
-	newSession := models.UserSession{
-		UserID:       user.ID,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresAt:    time.Now().Add(expiryTime),
-	}
-	session, err := s.sessionsRepository.CreateNewSession(&newSession)
-	if err != nil {
-		logger.Error("Failed to create new session")
-		return dto.LoginUserResponse{}, err
-	}
-
-	response := dto.LoginUserResponse{
-		AccessToken:  accessToken,
-		SessionID:    session.ID.String(),
-		RefreshToken: refreshToken,
-		ExpiresIn:    time.Now().Add(expiryTime),
-	}
-
-	return response, nil
+	return completeLogin(s.sessionsRepository, s.roleRepository, s.mfaRepository, user, pasetoSecret, logger)
 }
 
 // GetUserByID retrieves a user by their ID
@@ -157,34 +135,30 @@ func (s *userService) GetUserByID(ctx context.Context, userID string) (*models.U
 	return user, nil
 }
 
-// IncrementUserEventCount increments the event count for a user (organizer)
-func (s *userService) IncrementUserEventCount(ctx context.Context, userID string) error {
-	// Parse UUID
-	parsedID, err := uuid.Parse(userID)
+// BootstrapAdmin grants the admin role to the user registered with email, but
+// only while nobody holds it yet. Admins are otherwise appointed by admins.
+func (s *userService) BootstrapAdmin(email string, logger *zap.Logger) error {
+	assigned, err := s.roleRepository.RoleAssigned(models.RoleAdmin)
 	if err != nil {
-		return errors.New("invalid user ID format")
+		return err
+	}
+	if assigned {
+		logger.Info("An admin already exists, ignoring the bootstrap admin")
+		return nil
 	}
 
-	// For now, we'll just log that we're updating the count
-	// In a real implementation, you might have an events_count field in the users table
-	// or a separate organizer_stats table
-
-	// Check if user exists first
-	user, err := s.userRepository.GetUserById(parsedID)
+	user, err := s.userRepository.GetUserByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.Warn("Bootstrap admin has not registered yet", zap.String("email", email))
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	if user == nil {
-		return errors.New("user not found")
+	if err := s.roleRepository.AssignRole(user.ID, models.RoleAdmin); err != nil {
+		return err
 	}
-
-	// TODO: Implement actual count increment logic
-	// This could be:
-	// 1. Update a field in the users table
-	// 2. Insert/update a record in an organizer_stats table
-	// 3. Cache the count in Redis, etc.
-
-	// For now, this is a no-op that just validates the user exists
+	logger.Info("Granted the admin role to the bootstrap admin", zap.String("user_id", user.ID.String()))
 	return nil
 }